@@ -0,0 +1,30 @@
+package auth
+
+import "context"
+
+type contextKey string
+
+const (
+	tenantContextKey  contextKey = "tenant_id"
+	subjectContextKey contextKey = "subject"
+)
+
+// WithTenant 把校验通过的 tenant_id/subject 写入 context，供 addToChromaDB/vectorSearch
+// 等业务逻辑读取，取代它们原先直接读取 config.ChromDBTenants 的方式。
+func WithTenant(ctx context.Context, claims *Claims) context.Context {
+	ctx = context.WithValue(ctx, tenantContextKey, claims.TenantID)
+	ctx = context.WithValue(ctx, subjectContextKey, claims.Subject)
+	return ctx
+}
+
+// TenantFromContext 读取当前请求认证后的 tenant_id，ok 为 false 表示请求未经过 mw.Auth。
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantContextKey).(string)
+	return tenantID, ok
+}
+
+// SubjectFromContext 读取当前请求认证后的用户名。
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(subjectContextKey).(string)
+	return subject, ok
+}
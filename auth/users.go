@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// UserStore 用 SQLite 保存用户名/密码哈希和所属 tenant_id，支撑 /auth/register 和
+// /auth/login，让一次部署服务多个用户而不用共享同一个 Chroma tenant。
+type UserStore struct {
+	db *sql.DB
+}
+
+// NewUserStore 打开（或创建）dbPath 指向的 SQLite 文件，并确保 users 表存在。
+func NewUserStore(dbPath string) (*UserStore, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	schema := `CREATE TABLE IF NOT EXISTS users (
+		username TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL,
+		password_hash TEXT NOT NULL,
+		salt TEXT NOT NULL
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		return nil, err
+	}
+	return &UserStore{db: db}, nil
+}
+
+// Register 新建一个用户，tenantID 决定该用户之后能看到哪些向量记录。
+func (s *UserStore) Register(username, password, tenantID string) error {
+	salt, err := randomSalt()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		"INSERT INTO users (username, tenant_id, password_hash, salt) VALUES (?, ?, ?, ?)",
+		username, tenantID, hashPassword(password, salt), salt,
+	)
+	return err
+}
+
+// Authenticate 校验用户名/密码，成功时返回该用户所属的 tenant_id。
+func (s *UserStore) Authenticate(username, password string) (string, error) {
+	var tenantID, hash, salt string
+	err := s.db.QueryRow(
+		"SELECT tenant_id, password_hash, salt FROM users WHERE username = ?", username,
+	).Scan(&tenantID, &hash, &salt)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("用户不存在或密码错误")
+	}
+	if err != nil {
+		return "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashPassword(password, salt)), []byte(hash)) != 1 {
+		return "", fmt.Errorf("用户不存在或密码错误")
+	}
+	return tenantID, nil
+}
+
+func randomSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt), nil
+}
+
+func hashPassword(password, salt string) string {
+	sum := sha256.Sum256([]byte(salt + password))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
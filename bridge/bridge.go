@@ -0,0 +1,194 @@
+// Package bridge 把 wenko 的 Session 接到外部聊天网络：本地 AddEntry 产生的消息
+// fan-out 给绑定的每个 Bridge，每个 Bridge 收到的远程消息转换成 MessageType 后写回
+// 对应的 sessionID，和 matterbridge 把各个聊天网络接成一张图是同一个模型。
+package bridge
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"books-vector-api/internal/linkedhashmap"
+	"books-vector-api/log"
+	"books-vector-api/outbox"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bridge 是一个外部聊天网络的适配器。Name 用来在 Binding.Bridge 里引用它。
+type Bridge interface {
+	Send(ctx context.Context, msg outbox.MessageType) error
+	Receive() <-chan outbox.MessageType
+	Name() string
+}
+
+// Binding 把一个 wenko sessionID 和某个 Bridge 上的一个房间/频道/channel 绑在一起。
+type Binding struct {
+	SessionID string `yaml:"sessionId"`
+	Bridge    string `yaml:"bridge"`
+	Room      string `yaml:"room"`
+}
+
+// Config 是 bridge 的 YAML 配置：一组 sessionID <-> 远程房间的绑定关系。
+type Config struct {
+	Bindings []Binding `yaml:"bindings"`
+}
+
+// LoadConfig 读取 path 指向的 YAML 文件并解析成 Config。
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// seenTTL 是 ActionID 去重缓存的有效期：超过这个时间还没被再次看到的 ActionID 会被
+// 清理掉，避免 seen 这个 map 无限增长。
+const seenTTL = 5 * time.Minute
+
+// Manager 驱动 Config 里声明的全部绑定：为每个涉及到的 sessionID 订阅 Session，把
+// 新增的本地 entry fan-out 给绑定的 Bridge；同时为每个 Bridge 起一个 goroutine，把
+// 它 Receive() 到的远程消息写回对应 sessionID 的 Session。
+type Manager struct {
+	bridges  map[string]Bridge
+	bindings []Binding
+
+	seenMu sync.Mutex
+	seen   map[string]time.Time
+}
+
+// NewManager 创建一个按 cfg 里的绑定驱动 bridges 的 Manager。
+func NewManager(cfg *Config, bridges ...Bridge) *Manager {
+	m := &Manager{
+		bridges:  make(map[string]Bridge, len(bridges)),
+		bindings: cfg.Bindings,
+		seen:     make(map[string]time.Time),
+	}
+	for _, b := range bridges {
+		m.bridges[b.Name()] = b
+	}
+	return m
+}
+
+// markSeen 记录 actionID 出现过，返回这是不是它第一次出现。第二次出现说明这条消息
+// 刚被这个 Manager 转发出去又被原样送了回来（比如一个 bridge 把自己刚发的消息又当
+// 成新消息 Receive 回来），调用方应当丢弃它，否则本地和远程之间会来回无限转发。
+func (m *Manager) markSeen(actionID string) bool {
+	m.seenMu.Lock()
+	defer m.seenMu.Unlock()
+	now := time.Now()
+	for id, at := range m.seen {
+		if now.Sub(at) > seenTTL {
+			delete(m.seen, id)
+		}
+	}
+	if _, ok := m.seen[actionID]; ok {
+		return false
+	}
+	m.seen[actionID] = now
+	return true
+}
+
+// Run 启动全部订阅和转发 goroutine，阻塞直到 ctx 取消。
+func (m *Manager) Run(ctx context.Context, sessions *outbox.Session) {
+	bySession := make(map[string][]Binding)
+	for _, binding := range m.bindings {
+		bySession[binding.SessionID] = append(bySession[binding.SessionID], binding)
+	}
+
+	var wg sync.WaitGroup
+	for sessionID, bindings := range bySession {
+		ch, unsubscribe := sessions.Subscribe(sessionID)
+		wg.Add(1)
+		go func(sessionID string, bindings []Binding, ch <-chan outbox.MessageType, unsubscribe func()) {
+			defer wg.Done()
+			m.forwardLocal(ctx, sessionID, bindings, ch, unsubscribe)
+		}(sessionID, bindings, ch, unsubscribe)
+	}
+
+	for _, b := range m.bridges {
+		wg.Add(1)
+		go func(b Bridge) {
+			defer wg.Done()
+			m.forwardRemote(ctx, sessions, b)
+		}(b)
+	}
+
+	wg.Wait()
+}
+
+// forwardLocal 把 sessionID 上新增的本地 entry 发给 bindings 里引用的每个 Bridge。
+func (m *Manager) forwardLocal(ctx context.Context, sessionID string, bindings []Binding, ch <-chan outbox.MessageType, unsubscribe func()) {
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-ch:
+			if !ok {
+				return
+			}
+			if entry.ActionID != "" && !m.markSeen(entry.ActionID) {
+				continue
+			}
+			for _, binding := range bindings {
+				b, ok := m.bridges[binding.Bridge]
+				if !ok {
+					continue
+				}
+				if err := b.Send(ctx, withRoom(entry, binding.Room)); err != nil {
+					logWarn("投递到桥接失败", log.String("bridge", binding.Bridge), log.String("sessionID", sessionID), log.Err(err))
+				}
+			}
+		}
+	}
+}
+
+// withRoom 返回 entry 的一个浅拷贝，Meta 里写入 binding 配置的目标 room：每个 Bridge.Send
+// 实现（telegram.go/matrix.go/irc.go）都只认 Meta["room"] 找目的地，而这个字段只在入站回调
+// 里写过，本地产生的 entry 压根没有它——不拷贝直接改 entry.Payload.Meta 会把 room 污染进
+// 发布给其它订阅者的同一个共享对象里。
+func withRoom(entry outbox.MessageType, room string) outbox.MessageType {
+	meta := linkedhashmap.New[string, interface{}]()
+	entry.Payload.Meta.Range(func(key string, value interface{}) bool {
+		meta.Set(key, value)
+		return true
+	})
+	meta.Set("room", room)
+	entry.Payload.Meta = meta
+	return entry
+}
+
+// forwardRemote 把 b 收到的远程消息写回 b 上绑定了该消息来源房间（Meta["room"]）的每个
+// sessionID 对应的 Session。
+func (m *Manager) forwardRemote(ctx context.Context, sessions *outbox.Session, b Bridge) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-b.Receive():
+			if !ok {
+				return
+			}
+			if msg.ActionID == "" {
+				msg.ActionID = outbox.GenerateUUID()
+			}
+			if !m.markSeen(msg.ActionID) {
+				continue
+			}
+			room, _ := msg.Payload.Meta.Get("room")
+			for _, binding := range m.bindings {
+				if binding.Bridge != b.Name() || room != binding.Room {
+					continue
+				}
+				sessions.AddEntry(binding.SessionID, msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package bridge
+
+import (
+	"context"
+
+	"books-vector-api/internal/linkedhashmap"
+	"books-vector-api/log"
+	"books-vector-api/outbox"
+
+	"github.com/lrstanley/girc"
+)
+
+// IRCBridge 用一个 girc 客户端收发消息，Binding.Room 是频道名（形如 "#wenko"）。
+type IRCBridge struct {
+	client *girc.Client
+	out    chan outbox.MessageType
+}
+
+// NewIRCBridge 用 server/port/nick 构造一个 girc 客户端，注册 PRIVMSG 回调，加入
+// channels 里列出的频道，并在后台启动连接循环。
+func NewIRCBridge(server string, port int, nick string, channels []string) *IRCBridge {
+	client := girc.New(girc.Config{
+		Server: server,
+		Port:   port,
+		Nick:   nick,
+		User:   nick,
+		Name:   nick,
+	})
+
+	b := &IRCBridge{client: client, out: make(chan outbox.MessageType, 64)}
+
+	client.Handlers.AddBg(girc.CONNECTED, func(c *girc.Client, e girc.Event) {
+		for _, channel := range channels {
+			c.Cmd.Join(channel)
+		}
+	})
+
+	client.Handlers.AddBg(girc.PRIVMSG, func(c *girc.Client, e girc.Event) {
+		if e.Source == nil || e.Source.Name == nick || len(e.Params) < 2 {
+			return
+		}
+		meta := linkedhashmap.New[string, interface{}]()
+		meta.Set("source", "irc")
+		meta.Set("room", e.Params[0])
+		b.out <- outbox.MessageType{
+			Type: "text",
+			Payload: outbox.PayloadType{
+				Content: e.Last(),
+				Meta:    meta,
+			},
+		}
+	})
+
+	go func() {
+		if err := client.Connect(); err != nil {
+			logError("irc 连接中断", log.Err(err))
+		}
+	}()
+
+	return b
+}
+
+func (b *IRCBridge) Name() string { return "irc" }
+
+func (b *IRCBridge) Receive() <-chan outbox.MessageType {
+	return b.out
+}
+
+// Send 把 msg.Payload.Content 发到 msg.Payload.Meta["room"] 指定的频道。
+func (b *IRCBridge) Send(ctx context.Context, msg outbox.MessageType) error {
+	roomValue, _ := msg.Payload.Meta.Get("room")
+	room, _ := roomValue.(string)
+	b.client.Cmd.Message(room, msg.Payload.Content)
+	return nil
+}
@@ -0,0 +1,30 @@
+package bridge
+
+import "books-vector-api/log"
+
+// Log 是 main.go 在 init() 里通过 Init 注入的结构化日志器，和 outbox.Init 一样的接线
+// 方式，避免 bridge 反过来 import main。未注入时 logInfo/logWarn/logError 直接跳过。
+var Log *log.DailyLogger
+
+// Init 注入结构化日志器。
+func Init(logger *log.DailyLogger) {
+	Log = logger
+}
+
+func logInfo(message string, fields ...log.Field) {
+	if Log != nil {
+		Log.Info(message, fields...)
+	}
+}
+
+func logWarn(message string, fields ...log.Field) {
+	if Log != nil {
+		Log.Warn(message, fields...)
+	}
+}
+
+func logError(message string, fields ...log.Field) {
+	if Log != nil {
+		Log.Error(message, fields...)
+	}
+}
@@ -0,0 +1,75 @@
+package bridge
+
+import (
+	"context"
+
+	"books-vector-api/internal/linkedhashmap"
+	"books-vector-api/log"
+	"books-vector-api/outbox"
+
+	"github.com/matrix-org/gomatrix"
+)
+
+// MatrixBridge 用一个已登录的 gomatrix 客户端收发消息，Binding.Room 是 Matrix 的
+// room ID（形如 "!abc123:example.org"）。
+type MatrixBridge struct {
+	cli *gomatrix.Client
+	out chan outbox.MessageType
+}
+
+// NewMatrixBridge 用 homeserverURL/userID/accessToken 构造一个已登录的 gomatrix 客户端，
+// 注册消息事件回调，并在后台启动 Sync 循环。
+func NewMatrixBridge(homeserverURL, userID, accessToken string) (*MatrixBridge, error) {
+	cli, err := gomatrix.NewClient(homeserverURL, userID, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &MatrixBridge{cli: cli, out: make(chan outbox.MessageType, 64)}
+
+	syncer := cli.Syncer.(*gomatrix.DefaultSyncer)
+	syncer.OnEventType("m.room.message", func(event *gomatrix.Event) {
+		if event.Sender == userID {
+			return
+		}
+		body, ok := event.Body()
+		if !ok || body == "" {
+			return
+		}
+		meta := linkedhashmap.New[string, interface{}]()
+		meta.Set("source", "matrix")
+		meta.Set("room", event.RoomID)
+		b.out <- outbox.MessageType{
+			Type: "text",
+			Payload: outbox.PayloadType{
+				Content: body,
+				Meta:    meta,
+			},
+		}
+	})
+
+	go func() {
+		if err := cli.Sync(); err != nil {
+			logError("matrix 同步中断", log.Err(err))
+		}
+	}()
+
+	return b, nil
+}
+
+func (b *MatrixBridge) Name() string { return "matrix" }
+
+func (b *MatrixBridge) Receive() <-chan outbox.MessageType {
+	return b.out
+}
+
+// Send 把 msg.Payload.Content 发到 msg.Payload.Meta["room"] 指定的房间。
+func (b *MatrixBridge) Send(ctx context.Context, msg outbox.MessageType) error {
+	roomValue, _ := msg.Payload.Meta.Get("room")
+	room, _ := roomValue.(string)
+	_, err := b.cli.SendText(room, msg.Payload.Content)
+	if err != nil {
+		logError("matrix 发送失败", log.Err(err))
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package bridge
+
+import (
+	"context"
+	"strconv"
+
+	"books-vector-api/internal/linkedhashmap"
+	"books-vector-api/log"
+	"books-vector-api/outbox"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TelegramBridge 通过 go-telegram-bot-api 的长轮询收发消息，一个 Telegram chat
+// 对应 Binding.Room 里的 chat ID（字符串形式）。
+type TelegramBridge struct {
+	bot *tgbotapi.BotAPI
+	out chan outbox.MessageType
+}
+
+// NewTelegramBridge 用 token 登录 bot 并开始长轮询，收到的消息异步推进返回的
+// TelegramBridge 的 Receive channel。
+func NewTelegramBridge(token string) (*TelegramBridge, error) {
+	bot, err := tgbotapi.NewBotAPI(token)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &TelegramBridge{bot: bot, out: make(chan outbox.MessageType, 64)}
+	go b.poll()
+	return b, nil
+}
+
+func (b *TelegramBridge) Name() string { return "telegram" }
+
+func (b *TelegramBridge) poll() {
+	update := tgbotapi.NewUpdate(0)
+	update.Timeout = 60
+	for update := range b.bot.GetUpdatesChan(update) {
+		if update.Message == nil || update.Message.Text == "" {
+			continue
+		}
+		meta := linkedhashmap.New[string, interface{}]()
+		meta.Set("source", "telegram")
+		meta.Set("room", strconv.FormatInt(update.Message.Chat.ID, 10))
+		msg := outbox.MessageType{
+			Type: "text",
+			Payload: outbox.PayloadType{
+				Content: update.Message.Text,
+				Meta:    meta,
+			},
+		}
+		select {
+		case b.out <- msg:
+		default:
+			logWarn("telegram 接收队列已满，丢弃一条消息")
+		}
+	}
+}
+
+func (b *TelegramBridge) Receive() <-chan outbox.MessageType {
+	return b.out
+}
+
+// Send 把 msg.Payload.Content 发到 msg.Payload.Meta["room"] 指定的 chat；room 必须
+// 是能解析成 int64 的 Telegram chat ID（Manager 按 Binding.Room 把它填进来）。
+func (b *TelegramBridge) Send(ctx context.Context, msg outbox.MessageType) error {
+	roomValue, _ := msg.Payload.Meta.Get("room")
+	room, _ := roomValue.(string)
+	chatID, err := strconv.ParseInt(room, 10, 64)
+	if err != nil {
+		return err
+	}
+	_, err = b.bot.Send(tgbotapi.NewMessage(chatID, msg.Payload.Content))
+	if err != nil {
+		logError("telegram 发送失败", log.Err(err))
+	}
+	return err
+}
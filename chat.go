@@ -1,17 +1,22 @@
 package main
 
 import (
-	"bufio"
+	"books-vector-api/outbox"
+	"books-vector-api/outbox/sse"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type ChatRequest struct {
-	Messages []Message `json:"messages"`
-	Model    string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	Model     string    `json:"model"`
+	SessionID string    `json:"sessionId"`
 }
 
 type Message struct {
@@ -64,60 +69,192 @@ func Chat(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Printf("提示词: %v\n", chatReq.Messages)
 
+	// 有 sessionId 时才召回/记录对话记忆，老客户端不传就完全不受影响，和 ChatStream 的做法一致。
+	lastUserMessage := lastUserContent(chatReq.Messages)
+	messages := chatReq.Messages
+	if chatReq.SessionID != "" && lastUserMessage != "" {
+		if memoryContext, err := recallContext(r.Context(), chatReq.SessionID, lastUserMessage); err != nil {
+			fmt.Println("召回对话记忆失败:", err)
+		} else if memoryContext != "" {
+			messages = append([]Message{{Role: "system", Content: memoryContext}}, messages...)
+		}
+		if err := rememberTurn(r.Context(), chatReq.SessionID, "", "user", lastUserMessage); err != nil {
+			fmt.Println("记录对话记忆失败:", err)
+		}
+	}
+
 	requestBody, _ := json.Marshal(map[string]interface{}{
 		"model":    chatReq.Model,
-		"messages": chatReq.Messages,
+		"messages": messages,
 		"stream":   true,
 	})
 
-	req, _ := http.NewRequest("POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(requestBody))
+	eventType := "message"
+	var eventId int64 = 0
+
+	var assistantReply strings.Builder
+
+	// 用 sse.Client 代替手写的一次性 decoder 循环：连接中断时带着 Last-Event-ID 自动
+	// 重连并指数退避，和 recursivePlanningTask 同一套重连逻辑。
+	sseClient := sse.NewClient()
+	streamErr := sseClient.Stream(r.Context(), func(ctx context.Context, lastEventID string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(requestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+config.ModelProviderAPIKey)
+		req.Header.Set("Content-Type", "application/json")
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		return req, nil
+	}, func(event sse.Event) error {
+		if event.Data == "[DONE]" {
+			return io.EOF
+		}
+		var orResp OpenRouterResponse
+		if err := json.Unmarshal([]byte(event.Data), &orResp); err != nil || len(orResp.Choices) == 0 {
+			return nil
+		}
+		content := orResp.Choices[0].Delta.Content
+		if content == "" {
+			return nil
+		}
+		assistantReply.WriteString(content)
+
+		eventId++ // 每次发送时递增 ID
+
+		data := struct {
+			Content string `json:"content"`
+			Type    string `json:"type"`
+		}{
+			Content: content,
+			Type:    "text",
+		}
+		dataBytes, _ := json.Marshal(data)
+
+		fmt.Fprintf(w, "id: %d\n", eventId)
+		fmt.Fprintf(w, "event: %s\n", eventType)
+		fmt.Fprintf(w, "data: %s\n\n", dataBytes)
+		flusher.Flush()
+		return nil
+	})
+	if streamErr != nil {
+		fmt.Println("调用大模型失败:", streamErr)
+	}
+
+	if chatReq.SessionID != "" && assistantReply.Len() > 0 {
+		if err := rememberTurn(r.Context(), chatReq.SessionID, "", "assistant", assistantReply.String()); err != nil {
+			fmt.Println("记录对话记忆失败:", err)
+		}
+	}
+}
+
+// ChatStream 是 Chat 的 EventStream 版本，复用 outbox.EventStream 统一 SSE 帧格式并带心跳，
+// 取代 Chat 里手写的 fmt.Fprintf 拼帧。行为上和 Chat 等价，按 token 转发模型输出。
+func ChatStream(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "读取请求体失败", http.StatusBadRequest)
+		return
+	}
+
+	var chatReq ChatRequest
+	if err := json.Unmarshal(body, &chatReq); err != nil {
+		http.Error(w, "解析请求体失败", http.StatusBadRequest)
+		return
+	}
+
+	stream, err := outbox.NewEventStream(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stream.Heartbeat(15 * time.Second)
+	defer stream.Close()
+
+	stream.Send("statusText", map[string]string{"content": "连接成功，请稍后"})
+
+	// 有 sessionId 时才召回/记录对话记忆，老客户端不传就完全不受影响。
+	lastUserMessage := lastUserContent(chatReq.Messages)
+	messages := chatReq.Messages
+	if chatReq.SessionID != "" && lastUserMessage != "" {
+		if memoryContext, err := recallContext(r.Context(), chatReq.SessionID, lastUserMessage); err != nil {
+			fmt.Println("召回对话记忆失败:", err)
+		} else if memoryContext != "" {
+			messages = append([]Message{{Role: "system", Content: memoryContext}}, messages...)
+		}
+		if err := rememberTurn(r.Context(), chatReq.SessionID, "", "user", lastUserMessage); err != nil {
+			fmt.Println("记录对话记忆失败:", err)
+		}
+	}
+
+	requestBody, _ := json.Marshal(map[string]interface{}{
+		"model":    chatReq.Model,
+		"messages": messages,
+		"stream":   true,
+	})
+
+	req, err := http.NewRequestWithContext(r.Context(), "POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(requestBody))
+	if err != nil {
+		stream.Send("error", map[string]string{"content": err.Error()})
+		return
+	}
 	req.Header.Set("Authorization", "Bearer "+config.ModelProviderAPIKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		http.Error(w, "调用大模型失败: "+err.Error(), http.StatusInternalServerError)
+		stream.Send("error", map[string]string{"content": "调用大模型失败: " + err.Error()})
 		return
 	}
 	defer resp.Body.Close()
 
-	eventType := "message"
-	var eventId int64 = 0
+	var assistantReply strings.Builder
+
+	decoder := sse.NewDecoder(resp.Body)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		default:
+		}
+
+		event, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			stream.Send("error", map[string]string{"content": "读取大模型响应失败: " + err.Error()})
+			break
+		}
+		if event.Data == "[DONE]" {
+			break
+		}
+		var orResp OpenRouterResponse
+		if err := json.Unmarshal([]byte(event.Data), &orResp); err != nil || len(orResp.Choices) == 0 {
+			continue
+		}
+		content := orResp.Choices[0].Delta.Content
+		if content != "" {
+			stream.Send("text", map[string]string{"content": content})
+			assistantReply.WriteString(content)
+		}
+	}
+
+	if chatReq.SessionID != "" && assistantReply.Len() > 0 {
+		if err := rememberTurn(r.Context(), chatReq.SessionID, "", "assistant", assistantReply.String()); err != nil {
+			fmt.Println("记录对话记忆失败:", err)
+		}
+	}
+}
 
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if len(line) > 6 && line[:6] == "data: " {
-			data := line[6:]
-			if data == "[DONE]" {
-
-				break
-			}
-			var orResp OpenRouterResponse
-			if err := json.Unmarshal([]byte(data), &orResp); err == nil {
-				if len(orResp.Choices) > 0 {
-					content := orResp.Choices[0].Delta.Content
-					if content != "" {
-
-						eventId++ // 每次发送时递增 ID
-
-						data := struct {
-							Content string `json:"content"`
-							Type    string `json:"type"`
-						}{
-							Content: content,
-							Type:    "text",
-						}
-						dataBytes, _ := json.Marshal(data)
-
-						fmt.Fprintf(w, "id: %d\n", eventId)
-						fmt.Fprintf(w, "event: %s\n", eventType)
-						fmt.Fprintf(w, "data: %s\n\n", dataBytes)
-						flusher.Flush()
-					}
-				}
-			}
+// lastUserContent 返回消息列表里最后一条 role=="user" 的内容，用作记忆召回的查询文本。
+func lastUserContent(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
 		}
 	}
+	return ""
 }
@@ -2,11 +2,8 @@ package main
 
 import (
 	"books-vector-api/vector"
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
 )
 
 // WeightedText 结构体用于容纳文本及其权重
@@ -16,24 +13,18 @@ type WeightedText struct {
 }
 
 // 独立向量生成函数（复用存储逻辑中的核心部分） -> L2
-func generateEmbedding(text string) ([]float32, error) {
-	resp, err := http.Post(config.OllamaURL, "application/json",
-		bytes.NewBufferString(fmt.Sprintf(`{"model":"nomic-embed-text","prompt":"%s"}`, url.QueryEscape(text))))
+// ctx 由调用方传入（HTTP 请求的 r.Context()，或 CLI 场景下的 context.Background()），
+// 取消后直接中断 TextEmbedder.Embed 发起的底层 HTTP 请求，不再固定用 context.Background()。
+func generateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := TextEmbedder.Embed(ctx, []string{text})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var response OllamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, err
-	}
-
-	return response.Embedding, nil
+	return embeddings[0], nil
 }
 
 // generateWeightedEmbedding 函数用于将多个文本合并，按既定权重生成向量
-func generateWeightedEmbedding(weightedTexts []WeightedText) ([]float32, error) {
+func generateWeightedEmbedding(ctx context.Context, weightedTexts []WeightedText) ([]float32, error) {
 	if len(weightedTexts) == 0 {
 		return nil, fmt.Errorf("no texts provided for weighted embedding generation")
 	}
@@ -43,7 +34,7 @@ func generateWeightedEmbedding(weightedTexts []WeightedText) ([]float32, error)
 
 	for _, wt := range weightedTexts {
 		// fmt.Println("Generating embedding for text:", wt.Text)
-		embedding, err := generateEmbedding(wt.Text)
+		embedding, err := generateEmbedding(ctx, wt.Text)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate embedding for text \"%s\": %w", wt.Text, err)
 		}
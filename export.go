@@ -1,180 +1,142 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
+	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 	"time"
+
+	"books-vector-api/export"
 )
 
-// 用空行（两个连续换行符）来分隔段落，导出时替换为 "\n\n"
-// 避免使用特殊标记，保持文本自然，也利于后续处理和阅读
-func processContentForCSV(s string) (string, int) {
-	count := strings.Count(s, "$-$")
-	return s, count
-}
+// exportFormat/exportChunker/exportOutput/importFile 是进程启动时可选的一次性导出/
+// 导入开关：带上其中任意一个就只执行对应操作然后退出，不带就按原来的方式启动 HTTP 服务。
+// 例如 `./books-vector-api -export-format=parquet -export-chunker=recursive`。
+var (
+	exportFormat  = flag.String("export-format", "", "导出格式：jsonl/csv/parquet，不为空时只执行一次导出后退出")
+	exportChunker = flag.String("export-chunker", "recursive", "切分策略：fixed/sentence/recursive")
+	exportOutput  = flag.String("export-output", "", "导出文件路径，默认 export_<日期>.<格式>")
+	importFile    = flag.String("import-file", "", "要重新导入的文件路径（.jsonl/.csv/.parquet），不为空时只执行一次导入后退出")
+)
 
-func exportAllData() error {
-	today := time.Now().Format("20060102")          // YYYYMMDD format
-	filename := fmt.Sprintf("export_%s.csv", today) // Change to .csv
+// runCLIExportOrImport 在 exportFormat/importFile 任一被设置时执行对应的一次性操作，
+// 返回是否已经处理（处理完调用方应直接退出，不再启动 HTTP 服务）。
+func runCLIExportOrImport() (handled bool) {
+	if *importFile != "" {
+		count, err := runImport(*importFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "导入失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("导入完成，写入 %d 条记录\n", count)
+		return true
+	}
+	if *exportFormat != "" {
+		path, count, err := runExport(export.Format(*exportFormat), *exportChunker, *exportOutput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "导出失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("导出完成，%s 写入 %d 条记录\n", path, count)
+		return true
+	}
+	return false
+}
 
-	exportPath := filepath.Join(".", filename)
+// newChunker 按名字构造一个切分策略，使用各自合理的默认参数。
+func newChunker(name string) (export.Chunker, error) {
+	switch name {
+	case "", "recursive":
+		return export.RecursiveCharacterChunker{ChunkSize: 500, OverlapChars: 50}, nil
+	case "fixed":
+		return export.FixedTokenChunker{TokensPerChunk: 200, OverlapTokens: 20}, nil
+	case "sentence":
+		return export.SentenceBoundaryChunker{SentencesPerChunk: 5}, nil
+	default:
+		return nil, fmt.Errorf("未知的切分策略: %s", name)
+	}
+}
 
-	file, err := os.Create(exportPath)
+// listExportDocuments 按 export.DocumentLister 的形状分页列出全部文档（不按租户过滤，
+// 导出本身是管理员级别的全量操作），content 取自 metadata["content"]。
+func listExportDocuments(limit, offset int) ([]export.Document, error) {
+	result, err := Store.List(limit, offset)
 	if err != nil {
-		return fmt.Errorf("failed to create export file %s: %w", exportPath, err)
+		return nil, err
 	}
-	defer file.Close()
-
-	// Add a CSV writer
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	const limit = 100
-	offset := 0
-	maxColumns := 0
-	var allProcessedContent [][]string // To store all processed content rows
-
-	fmt.Printf("Starting data export to %s...\n", exportPath)
-
-	for {
-		documents, err := listDocuments(limit, offset)
+	if len(result.IDs) > 0 {
+		withEmbeddings, err := Store.Get(result.IDs, []string{"embeddings"})
 		if err != nil {
-			return fmt.Errorf("failed to list documents from ChromaDB at offset %d: %w", offset, err)
-		}
-
-		if len(documents.IDs) == 0 {
-			break
+			return nil, err
 		}
-
-		for _, metadata := range documents.Metadatas {
-			if content, ok := metadata["content"]; ok {
-				if contentStr, isString := content.(string); isString {
-					processedContent, count := processContentForCSV(contentStr)
-					if count > maxColumns {
-						maxColumns = count
-					}
-					// Split the processed content by comma to get individual fields
-					fields := strings.Split(processedContent, "$-$")
-					allProcessedContent = append(allProcessedContent, fields)
-				} else {
-					fmt.Printf("Warning: 'content' in metadata is not a string, skipping: %v\n", content)
-				}
-			} else {
-				fmt.Println("Warning: 'content' key not found in metadata for a document.")
+		byID := make(map[string][]float32, len(withEmbeddings.IDs))
+		for i, id := range withEmbeddings.IDs {
+			if i < len(withEmbeddings.Embeddings) {
+				byID[id] = withEmbeddings.Embeddings[i]
 			}
 		}
-
-		offset += len(documents.IDs)
-
-		if len(documents.IDs) < limit {
-			break
+		result.Embeddings = make([][]float32, len(result.IDs))
+		for i, id := range result.IDs {
+			result.Embeddings[i] = byID[id]
 		}
 	}
-
-	// Write header row
-	if maxColumns > 0 {
-		header := make([]string, maxColumns+1) // +1 for the first column (index 0)
-		for i := 0; i <= maxColumns; i++ {
-			header[i] = fmt.Sprintf("%d", i+1)
+	docs := make([]export.Document, 0, len(result.IDs))
+	for i, metadata := range result.Metadatas {
+		content, _ := metadata["content"].(string)
+		doc := export.Document{
+			ID:       result.IDs[i],
+			Content:  content,
+			Metadata: metadata,
 		}
-		err := writer.Write(header)
-		if err != nil {
-			return fmt.Errorf("failed to write CSV header: %w", err)
+		if i < len(result.Embeddings) {
+			doc.Embedding = result.Embeddings[i]
 		}
+		docs = append(docs, doc)
 	}
+	return docs, nil
+}
 
-	// Write all processed content
-	for _, row := range allProcessedContent {
-		// Ensure all rows have the same number of columns as maxColumns + 1
-		// Pad with empty strings if necessary
-		for len(row) <= maxColumns {
-			row = append(row, "")
-		}
-		err := writer.Write(row)
-		if err != nil {
-			return fmt.Errorf("failed to write CSV row: %w", err)
-		}
+// storeImportedRecord 把重新导入的一条记录当作新文本重新生成向量并写回 Store，
+// 不复用导出时留存的 embedding，避免导入批次和当前 embedding 模型的维度不一致。
+// 导入是一次性 CLI 操作，没有上游请求可以派生 context，直接用 context.Background()。
+//
+// id 由 record.ContentHash() 派生而不是随机 UUID：Store.Upsert 按 id 覆盖写入，
+// 同一段内容不管重放几次都落在同一个 id 上，这样重复执行 -import-file 不会产生重复记录。
+func storeImportedRecord(record export.Record) error {
+	ctx := context.Background()
+	texts := []WeightedText{{Text: record.Text, Weight: 1}}
+	embedding, err := generateWeightedEmbedding(ctx, texts)
+	if err != nil {
+		return err
 	}
-
-	fmt.Printf("Data export completed successfully to %s.\n", exportPath)
-	return nil
+	id := "import-" + record.ContentHash()
+	_, err = addToChromaDB(id, embedding, texts, "")
+	return err
 }
 
-// func importData(filename string) error {
-// 	filePath := filepath.Join(".", filename) // 约定文件在项目根目录
-
-// 	// 1. 读取并处理文件内容
-// 	file, err := os.Open(filePath)
-// 	if err != nil {
-// 		return fmt.Errorf("无法打开文件 %s: %w", filePath, err)
-// 	}
-// 	defer file.Close() // 确保文件在处理后关闭
-
-// 	scanner := bufio.NewScanner(file)
-// 	lineNum := 0
-// 	for scanner.Scan() {
-// 		lineNum++
-// 		content := strings.TrimSpace(scanner.Text())
-// 		if content == "" {
-// 			continue // 跳过空行
-// 		}
-
-// 		fmt.Printf("正在导入第 %d 行: %s\n", lineNum, content)
-// 		_, err := generateAndStore(content)
-// 		if err != nil {
-// 			// 导入失败则立即返回错误
-// 			return fmt.Errorf("为第 %d 行内容生成并存储失败: %w", lineNum, err)
-// 		}
-// 	}
-
-// 	if err := scanner.Err(); err != nil {
-// 		return fmt.Errorf("读取文件 %s 时出错: %w", filePath, err)
-// 	}
-
-// 	// 2. 在文件第一行添加 "imported_当前日期" 标记
-// 	today := time.Now().Format("20060102") // YYYYMMDD 格式
-// 	importTag := fmt.Sprintf("imported_%s\n", today)
-
-// 	// 创建一个临时文件
-// 	tempFile, err := os.CreateTemp(".", filename+".tmp") // 在当前目录创建临时文件
-// 	if err != nil {
-// 		return fmt.Errorf("无法创建临时文件: %w", err)
-// 	}
-// 	defer os.Remove(tempFile.Name()) // 确保在函数退出时（或发生错误时）清理临时文件
-// 	defer tempFile.Close()
-
-// 	// 将导入标记写入临时文件
-// 	_, err = tempFile.WriteString(importTag)
-// 	if err != nil {
-// 		return fmt.Errorf("无法将导入标记写入临时文件: %w", err)
-// 	}
-
-// 	// 重新打开原始文件以复制其内容
-// 	originalFile, err := os.Open(filePath)
-// 	if err != nil {
-// 		return fmt.Errorf("无法重新打开原始文件 %s 进行复制: %w", filePath, err)
-// 	}
-// 	defer originalFile.Close()
-
-// 	// 将原始文件内容复制到临时文件
-// 	_, err = io.Copy(tempFile, originalFile)
-// 	if err != nil {
-// 		return fmt.Errorf("无法将原始文件内容复制到临时文件: %w", err)
-// 	}
-
-// 	// 在重命名之前关闭两个文件
-// 	originalFile.Close()
-// 	tempFile.Close()
+// runExport 分页遍历 Store 里的全部文档，用给定的切分策略切分后导出成 path（为空
+// 时用 export_<日期>.<格式> 默认名），取代原来按 "$-$" 拆列、靠 maxColumns 补空的 CSV 导出。
+func runExport(format export.Format, chunkerName, path string) (string, int, error) {
+	chunker, err := newChunker(chunkerName)
+	if err != nil {
+		return "", 0, err
+	}
+	if path == "" {
+		path = fmt.Sprintf("export_%s.%s", time.Now().Format("20060102"), format)
+	}
 
-// 	// 将临时文件重命名为原始文件，覆盖原文件
-// 	err = os.Rename(tempFile.Name(), filePath)
-// 	if err != nil {
-// 		return fmt.Errorf("无法将临时文件重命名为原始文件: %w", err)
-// 	}
+	exporter := export.NewExporter(listExportDocuments, chunker)
+	count, err := exporter.Export(path, format)
+	if err != nil {
+		return "", 0, err
+	}
+	return path, count, nil
+}
 
-// 	fmt.Printf("数据已成功从 %s 导入并添加标记。\n", filePath)
-// 	return nil
-// }
+// runImport 把 path 指向的导出文件（jsonl/csv/parquet 任一）重新写回向量库，
+// 按内容哈希去重。
+func runImport(path string) (int, error) {
+	importer := export.NewImporter(storeImportedRecord)
+	return importer.Import(path)
+}
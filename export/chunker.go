@@ -0,0 +1,208 @@
+// Package export 把 ChromaDB 里分页存储的文档切分成适合喂给嵌入/训练流水线的片段，
+// 并支持导出成 JSONL、CSV、Parquet 三种格式之一，以及把任意一种格式重新导入回向量库。
+package export
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Chunk 是一段切分后的文本，Index 是它在原文档里的序号（从 0 开始）。
+type Chunk struct {
+	Index int
+	Text  string
+}
+
+// Chunker 把一篇文档的正文切成若干 Chunk，导出流水线对每个 Chunk 都生成一条 Record。
+type Chunker interface {
+	Chunk(text string) []Chunk
+}
+
+// reindex 过滤掉空白片段并按留下来的顺序重新编号，得到最终的 Chunk 列表。
+func reindex(texts []string) []Chunk {
+	chunks := make([]Chunk, 0, len(texts))
+	for _, t := range texts {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		chunks = append(chunks, Chunk{Index: len(chunks), Text: t})
+	}
+	return chunks
+}
+
+// FixedTokenChunker 按空白切出的 token（约等于单词）数量切分，支持 token 级别的重叠，
+// 是最简单、最可预测的策略。
+type FixedTokenChunker struct {
+	TokensPerChunk int
+	OverlapTokens  int
+}
+
+func (c FixedTokenChunker) Chunk(text string) []Chunk {
+	tokens := strings.Fields(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+	size := c.TokensPerChunk
+	if size <= 0 {
+		size = 200
+	}
+	overlap := c.OverlapTokens
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+	step := size - overlap
+
+	var texts []string
+	for start := 0; start < len(tokens); start += step {
+		end := start + size
+		if end > len(tokens) {
+			end = len(tokens)
+		}
+		texts = append(texts, strings.Join(tokens[start:end], " "))
+		if end == len(tokens) {
+			break
+		}
+	}
+	return reindex(texts)
+}
+
+// sentenceBoundary 匹配中英文常见的句末标点，后面允许跟闭引号/括号。
+var sentenceBoundary = regexp.MustCompile(`[.!?。！？]+["')\]」』]*\s*`)
+
+// splitSentences 按 Unicode 标点把文本切成句子；没有命中任何终止符时把整段当成一句。
+func splitSentences(text string) []string {
+	var sentences []string
+	last := 0
+	for _, loc := range sentenceBoundary.FindAllStringIndex(text, -1) {
+		sentences = append(sentences, text[last:loc[1]])
+		last = loc[1]
+	}
+	if last < len(text) {
+		sentences = append(sentences, text[last:])
+	}
+	return sentences
+}
+
+// SentenceBoundaryChunker 按句子切分后，每 SentencesPerChunk 句拼成一个 chunk，
+// 避免像固定 token 切分那样把一句话从中间切断。
+type SentenceBoundaryChunker struct {
+	SentencesPerChunk int
+}
+
+func (c SentenceBoundaryChunker) Chunk(text string) []Chunk {
+	sentences := splitSentences(text)
+	if len(sentences) == 0 {
+		return nil
+	}
+	perChunk := c.SentencesPerChunk
+	if perChunk <= 0 {
+		perChunk = 5
+	}
+
+	var texts []string
+	for start := 0; start < len(sentences); start += perChunk {
+		end := start + perChunk
+		if end > len(sentences) {
+			end = len(sentences)
+		}
+		texts = append(texts, strings.Join(sentences[start:end], ""))
+	}
+	return reindex(texts)
+}
+
+// RecursiveCharacterChunker 参照常见的 recursive-character 切分法：优先按段落
+// （\n\n）切，段落仍然超长时按句子切，句子仍然超长时按字符硬切，每个产出的 chunk
+// 之间保留 OverlapChars 个字符的重叠，减少切点处上下文丢失。
+type RecursiveCharacterChunker struct {
+	ChunkSize    int
+	OverlapChars int
+}
+
+func (c RecursiveCharacterChunker) Chunk(text string) []Chunk {
+	size := c.ChunkSize
+	if size <= 0 {
+		size = 500
+	}
+	overlap := c.OverlapChars
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	pieces := splitRecursively(text, size)
+	texts := mergeWithOverlap(pieces, size, overlap)
+	return reindex(texts)
+}
+
+// splitRecursively 依次尝试按段落、句子、字符切分，任何一段仍然超过 size 才继续往
+// 更细的粒度切，否则保留原样。
+func splitRecursively(text string, size int) []string {
+	if len([]rune(text)) <= size {
+		return []string{text}
+	}
+
+	paragraphs := strings.Split(text, "\n\n")
+	if len(paragraphs) > 1 {
+		var out []string
+		for _, p := range paragraphs {
+			out = append(out, splitRecursively(p, size)...)
+		}
+		return out
+	}
+
+	sentences := splitSentences(text)
+	if len(sentences) > 1 {
+		var out []string
+		for _, s := range sentences {
+			out = append(out, splitRecursively(s, size)...)
+		}
+		return out
+	}
+
+	// 连一句话都超长，按字符硬切。
+	runes := []rune(text)
+	var out []string
+	for start := 0; start < len(runes); start += size {
+		end := start + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		out = append(out, string(runes[start:end]))
+	}
+	return out
+}
+
+// mergeWithOverlap 把切出来的碎片重新拼成不超过 size 的 chunk，相邻 chunk 之间保留
+// overlap 个字符的重叠；重叠部分加上下一块碎片会超过 size 时，那个 chunk 不带重叠。
+func mergeWithOverlap(pieces []string, size, overlap int) []string {
+	var texts []string
+	var current strings.Builder
+	for _, piece := range pieces {
+		if current.Len() > 0 && len([]rune(current.String()))+1+len([]rune(piece)) > size {
+			texts = append(texts, current.String())
+			tail := tailRunes(current.String(), overlap)
+			current.Reset()
+			if len([]rune(tail))+1+len([]rune(piece)) <= size {
+				current.WriteString(tail)
+			}
+		}
+		if current.Len() > 0 {
+			current.WriteString(" ")
+		}
+		current.WriteString(piece)
+	}
+	if current.Len() > 0 {
+		texts = append(texts, current.String())
+	}
+	return texts
+}
+
+// tailRunes 返回 s 末尾的 n 个字符，用作下一个 chunk 开头的重叠部分；n<=0 或 s 不超过
+// n 个字符时返回空字符串。
+func tailRunes(s string, n int) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return ""
+	}
+	return string(runes[len(runes)-n:])
+}
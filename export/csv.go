@@ -0,0 +1,87 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// csvHeader 是导出 CSV 固定的四列表头，取代原来靠 "$-$" 拆列、按 maxColumns 补空
+// 产出的稀疏表格。
+var csvHeader = []string{"id", "chunk_index", "text", "metadata_json"}
+
+// WriteCSV 把 records 写成 id,chunk_index,text,metadata_json 四列的 CSV。
+func WriteCSV(path string, records []Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建导出文件失败: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	defer w.Flush()
+
+	if err := w.Write(csvHeader); err != nil {
+		return fmt.Errorf("写入 CSV 表头失败: %w", err)
+	}
+	for _, r := range records {
+		metadataJSON, err := json.Marshal(r.Metadata)
+		if err != nil {
+			return fmt.Errorf("序列化 metadata 失败: %w", err)
+		}
+		row := []string{r.ID, strconv.Itoa(r.ChunkIndex), r.Text, string(metadataJSON)}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("写入 CSV 行失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadCSV 读回一个 WriteCSV 导出的文件，source_id 从 id 的 "-<chunk_index>" 后缀还原。
+func ReadCSV(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开导出文件失败: %w", err)
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析 CSV 失败: %w", err)
+	}
+	if len(rows) <= 1 {
+		return nil, nil
+	}
+
+	records := make([]Record, 0, len(rows)-1)
+	for _, row := range rows[1:] { // 跳过表头
+		if len(row) != 4 {
+			continue
+		}
+		chunkIndex, _ := strconv.Atoi(row[1])
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(row[3]), &metadata); err != nil {
+			return nil, fmt.Errorf("解析 metadata_json 失败: %w", err)
+		}
+		records = append(records, Record{
+			ID:         row[0],
+			SourceID:   sourceIDFromRecordID(row[0], chunkIndex),
+			ChunkIndex: chunkIndex,
+			Text:       row[2],
+			Metadata:   metadata,
+		})
+	}
+	return records, nil
+}
+
+// sourceIDFromRecordID 从 "<sourceID>-<chunkIndex>" 形式的记录 id 还原出 sourceID。
+func sourceIDFromRecordID(id string, chunkIndex int) string {
+	suffix := fmt.Sprintf("-%d", chunkIndex)
+	if strings.HasSuffix(id, suffix) {
+		return id[:len(id)-len(suffix)]
+	}
+	return id
+}
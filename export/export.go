@@ -0,0 +1,94 @@
+package export
+
+import "fmt"
+
+// Document 是 Exporter 从向量库分页读出的一条原始记录，导出前还没被切分。
+type Document struct {
+	ID        string
+	Content   string
+	Metadata  map[string]interface{}
+	Embedding []float32
+}
+
+// DocumentLister 分页读取向量库里的文档；和 main 包里 listDocuments 的分页方式一致，
+// 用函数钩子解耦 export 包和 main 包，参考 outbox.VectorSearchFunc 的接线方式。
+type DocumentLister func(limit, offset int) ([]Document, error)
+
+// Format 是导出文件的格式，由 CLI flag 选择。
+type Format string
+
+const (
+	FormatJSONL   Format = "jsonl"
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// Exporter 分页遍历 DocumentLister 给出的文档，用 Chunker 切分后按 Format 写出去。
+type Exporter struct {
+	List     DocumentLister
+	Chunker  Chunker
+	PageSize int
+}
+
+// defaultPageSize 和 exportAllData 原先用的分页大小保持一致。
+const defaultPageSize = 100
+
+// NewExporter 创建一个每页 100 条文档的 Exporter。
+func NewExporter(list DocumentLister, chunker Chunker) *Exporter {
+	return &Exporter{List: list, Chunker: chunker, PageSize: defaultPageSize}
+}
+
+// Export 把全部文档切分后写入 path，返回写出的记录数。
+func (e *Exporter) Export(path string, format Format) (int, error) {
+	records, err := e.collect()
+	if err != nil {
+		return 0, err
+	}
+	switch format {
+	case FormatJSONL:
+		return len(records), WriteJSONL(path, records)
+	case FormatCSV:
+		return len(records), WriteCSV(path, records)
+	case FormatParquet:
+		return len(records), WriteParquet(path, records)
+	default:
+		return 0, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// collect 分页读出全部文档并切分成 Record。
+func (e *Exporter) collect() ([]Record, error) {
+	pageSize := e.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	var records []Record
+	offset := 0
+	for {
+		docs, err := e.List(pageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("分页读取文档失败（offset=%d）: %w", offset, err)
+		}
+		if len(docs) == 0 {
+			break
+		}
+		for _, doc := range docs {
+			for _, chunk := range e.Chunker.Chunk(doc.Content) {
+				records = append(records, Record{
+					ID:         fmt.Sprintf("%s-%d", doc.ID, chunk.Index),
+					SourceID:   doc.ID,
+					ChunkIndex: chunk.Index,
+					Text:       chunk.Text,
+					Metadata:   doc.Metadata,
+					Embedding:  doc.Embedding,
+				})
+			}
+		}
+		offset += len(docs)
+		if len(docs) < pageSize {
+			break
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DocumentStorer 把一条记录写回向量库，和 main 包里 generateAndStore 的职责对应，
+// 用函数钩子解耦 export 包和 main 包。
+type DocumentStorer func(record Record) error
+
+// Importer 把任意一种导出格式重新读回向量库，按内容哈希去重：同一段文本不管是
+// 在哪次导出、哪个 chunk index 下产出的，都只会被写入一次。
+type Importer struct {
+	Store DocumentStorer
+}
+
+// NewImporter 创建一个 Importer。
+func NewImporter(store DocumentStorer) *Importer {
+	return &Importer{Store: store}
+}
+
+// Import 按 path 的扩展名选择解析格式，读出记录后按内容哈希去重再写回向量库，
+// 返回实际写入（未被判定为重复）的记录数。
+func (im *Importer) Import(path string) (int, error) {
+	records, err := readRecords(path)
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[string]struct{}, len(records))
+	imported := 0
+	for _, r := range records {
+		hash := r.ContentHash()
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+
+		if err := im.Store(r); err != nil {
+			return imported, fmt.Errorf("导入记录 %s 失败: %w", r.ID, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// readRecords 按扩展名分派到对应格式的读取函数。
+func readRecords(path string) ([]Record, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl":
+		return ReadJSONL(path)
+	case ".csv":
+		return ReadCSV(path)
+	case ".parquet":
+		return ReadParquet(path)
+	default:
+		return nil, fmt.Errorf("无法从扩展名推断导入格式: %s", path)
+	}
+}
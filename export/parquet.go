@@ -0,0 +1,116 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetNumParallel 是 parquet-go 读写时用的并行 goroutine 数，导出的数据量不大，
+// 给个固定值即可。
+const parquetNumParallel = 4
+
+// parquetRow 是 Record 在 Parquet 里的落地 schema：metadata 序列化成一列 JSON 字符串，
+// embedding 按请求存成 LIST<FLOAT>。
+type parquetRow struct {
+	ID           string    `parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	SourceID     string    `parquet:"name=source_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ChunkIndex   int32     `parquet:"name=chunk_index, type=INT32"`
+	Text         string    `parquet:"name=text, type=BYTE_ARRAY, convertedtype=UTF8"`
+	MetadataJSON string    `parquet:"name=metadata_json, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Embedding    []float32 `parquet:"name=embedding, type=LIST, valuetype=FLOAT"`
+}
+
+func toParquetRow(r Record) (parquetRow, error) {
+	metadataJSON, err := json.Marshal(r.Metadata)
+	if err != nil {
+		return parquetRow{}, fmt.Errorf("序列化 metadata 失败: %w", err)
+	}
+	return parquetRow{
+		ID:           r.ID,
+		SourceID:     r.SourceID,
+		ChunkIndex:   int32(r.ChunkIndex),
+		Text:         r.Text,
+		MetadataJSON: string(metadataJSON),
+		Embedding:    r.Embedding,
+	}, nil
+}
+
+func fromParquetRow(row parquetRow) (Record, error) {
+	var metadata map[string]interface{}
+	if err := json.Unmarshal([]byte(row.MetadataJSON), &metadata); err != nil {
+		return Record{}, fmt.Errorf("解析 metadata_json 失败: %w", err)
+	}
+	return Record{
+		ID:         row.ID,
+		SourceID:   row.SourceID,
+		ChunkIndex: int(row.ChunkIndex),
+		Text:       row.Text,
+		Metadata:   metadata,
+		Embedding:  row.Embedding,
+	}, nil
+}
+
+// WriteParquet 把 records 写成 Parquet 文件，embedding 存成 LIST<FLOAT> 列。
+func WriteParquet(path string, records []Record) error {
+	fw, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		return fmt.Errorf("创建 Parquet 文件失败: %w", err)
+	}
+	defer fw.Close()
+
+	pw, err := writer.NewParquetWriter(fw, new(parquetRow), parquetNumParallel)
+	if err != nil {
+		return fmt.Errorf("创建 Parquet writer 失败: %w", err)
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	for _, r := range records {
+		row, err := toParquetRow(r)
+		if err != nil {
+			return err
+		}
+		if err := pw.Write(row); err != nil {
+			return fmt.Errorf("写入 Parquet 行失败: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return fmt.Errorf("结束 Parquet 写入失败: %w", err)
+	}
+	return nil
+}
+
+// ReadParquet 读回一个 WriteParquet 导出的文件。
+func ReadParquet(path string) ([]Record, error) {
+	fr, err := local.NewLocalFileReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开 Parquet 文件失败: %w", err)
+	}
+	defer fr.Close()
+
+	pr, err := reader.NewParquetReader(fr, new(parquetRow), parquetNumParallel)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Parquet reader 失败: %w", err)
+	}
+	defer pr.ReadStop()
+
+	total := int(pr.GetNumRows())
+	rows := make([]parquetRow, total)
+	if err := pr.Read(&rows); err != nil {
+		return nil, fmt.Errorf("读取 Parquet 行失败: %w", err)
+	}
+
+	records := make([]Record, 0, total)
+	for _, row := range rows {
+		record, err := fromParquetRow(row)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
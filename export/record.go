@@ -0,0 +1,24 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Record 是导出流水线里统一的一条输出单元：某篇文档切出的一个 chunk，连同它的来源、
+// 元数据和向量，JSONL/CSV/Parquet 三种格式都从这个结构渲染。
+type Record struct {
+	ID         string                 `json:"id"`
+	SourceID   string                 `json:"source_id"`
+	ChunkIndex int                    `json:"chunk_index"`
+	Text       string                 `json:"text"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Embedding  []float32              `json:"embedding,omitempty"`
+}
+
+// ContentHash 是 Text 的 sha256 摘要，重新导入时用它判断一条记录是否已经存在，
+// 取代按 ID 去重——同一段内容可能在不同批次导出时拿到不同的 chunk ID。
+func (r Record) ContentHash() string {
+	sum := sha256.Sum256([]byte(r.Text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,170 @@
+// Package linkedhashmap 提供一个记住插入顺序的 map：container/list 维护顺序，
+// map[K]*list.Element 提供 O(1) 查找，MarshalJSON/UnmarshalJSON 按同样的顺序读写 key，
+// 解决 map[string]interface{} 在签名、diff、缓存 key 这几个场景下 key 顺序不确定的问题。
+package linkedhashmap
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+)
+
+// entry 是 order 链表里每个节点保存的键值对。
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// OrderedMap 是一个保留插入顺序的 map，零值不可用，必须用 New 构造。
+type OrderedMap[K comparable, V any] struct {
+	order *list.List
+	index map[K]*list.Element
+}
+
+// New 创建一个空的 OrderedMap。
+func New[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{
+		order: list.New(),
+		index: make(map[K]*list.Element),
+	}
+}
+
+// Set 写入 key/value：key 已存在时原地更新值，不改变它在顺序里的位置；
+// key 不存在时追加到末尾。
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	if el, ok := m.index[key]; ok {
+		el.Value.(*entry[K, V]).value = value
+		return
+	}
+	el := m.order.PushBack(&entry[K, V]{key: key, value: value})
+	m.index[key] = el
+}
+
+// Get 返回 key 对应的值，m 为 nil 或 key 不存在时 ok 为 false。
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	if m == nil {
+		var zero V
+		return zero, false
+	}
+	el, ok := m.index[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return el.Value.(*entry[K, V]).value, true
+}
+
+// Delete 从 m 里移除 key，不存在时是 no-op。
+func (m *OrderedMap[K, V]) Delete(key K) {
+	if m == nil {
+		return
+	}
+	el, ok := m.index[key]
+	if !ok {
+		return
+	}
+	m.order.Remove(el)
+	delete(m.index, key)
+}
+
+// Len 返回 m 里的键值对数量，nil 视为 0。
+func (m *OrderedMap[K, V]) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.order.Len()
+}
+
+// Range 按插入顺序依次调用 fn，fn 返回 false 时提前停止。
+func (m *OrderedMap[K, V]) Range(fn func(key K, value V) bool) {
+	if m == nil {
+		return
+	}
+	for el := m.order.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry[K, V])
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// MarshalJSON 按插入顺序写出 {"k1":v1,"k2":v2,...}；key 的类型必须正好是 string，
+// 其它 key 类型（包括底层类型是 string 的自定义类型）都会报错。
+func (m *OrderedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	var rangeErr error
+	m.Range(func(key K, value V) bool {
+		keyStr, ok := any(key).(string)
+		if !ok {
+			rangeErr = fmt.Errorf("linkedhashmap: 序列化只支持 string 类型的 key，实际是 %T", key)
+			return false
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		keyJSON, err := json.Marshal(keyStr)
+		if err != nil {
+			rangeErr = err
+			return false
+		}
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			rangeErr = err
+			return false
+		}
+		buf.Write(valueJSON)
+		return true
+	})
+	if rangeErr != nil {
+		return nil, rangeErr
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON 用 json.Decoder 逐个 token 读取，按 JSON 源文本里的顺序重建 m——
+// 标准库把同样的内容解进 map[string]interface{} 之后顺序就丢了，这个方法是本包存在的意义。
+func (m *OrderedMap[K, V]) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok || delim != '{' {
+		return fmt.Errorf("linkedhashmap: 期望 JSON object，实际是 %v", tok)
+	}
+
+	m.order = list.New()
+	m.index = make(map[K]*list.Element)
+
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		keyStr, ok := keyTok.(string)
+		if !ok {
+			return fmt.Errorf("linkedhashmap: 期望 string 类型的 key，实际是 %v", keyTok)
+		}
+		key, ok := any(keyStr).(K)
+		if !ok {
+			return fmt.Errorf("linkedhashmap: key 类型不是 string")
+		}
+
+		var value V
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		m.Set(key, value)
+	}
+
+	_, err = dec.Token() // 消费结尾的 '}'
+	return err
+}
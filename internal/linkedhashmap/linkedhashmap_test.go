@@ -0,0 +1,153 @@
+package linkedhashmap
+
+import "testing"
+
+func TestOrderedMapSetGet(t *testing.T) {
+	m := New[string, interface{}]()
+	m.Set("a", 1)
+	m.Set("b", "two")
+
+	if v, ok := m.Get("a"); !ok || v != 1 {
+		t.Fatalf("期望 a=1，实际 v=%v ok=%v", v, ok)
+	}
+	if v, ok := m.Get("b"); !ok || v != "two" {
+		t.Fatalf("期望 b=\"two\"，实际 v=%v ok=%v", v, ok)
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Fatalf("不存在的 key 应该返回 ok=false")
+	}
+}
+
+func TestOrderedMapSetExistingKeyUpdatesInPlaceWithoutReordering(t *testing.T) {
+	m := New[string, interface{}]()
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("a", 99) // 更新已存在的 key，不应该改变它在顺序里的位置
+
+	var keys []string
+	m.Range(func(key string, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fatalf("期望顺序保持 [a b]，实际 %v", keys)
+	}
+	if v, _ := m.Get("a"); v != 99 {
+		t.Fatalf("期望 a 被更新为 99，实际 %v", v)
+	}
+}
+
+func TestOrderedMapRangePreservesInsertionOrder(t *testing.T) {
+	m := New[string, interface{}]()
+	order := []string{"z", "a", "m", "b"}
+	for _, k := range order {
+		m.Set(k, nil)
+	}
+
+	var got []string
+	m.Range(func(key string, value interface{}) bool {
+		got = append(got, key)
+		return true
+	})
+	for i, k := range order {
+		if got[i] != k {
+			t.Fatalf("期望按插入顺序 %v，实际 %v", order, got)
+		}
+	}
+}
+
+func TestOrderedMapRangeStopsWhenFnReturnsFalse(t *testing.T) {
+	m := New[string, interface{}]()
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Set("c", 3)
+
+	var visited []string
+	m.Range(func(key string, value interface{}) bool {
+		visited = append(visited, key)
+		return key != "b"
+	})
+	if len(visited) != 2 {
+		t.Fatalf("返回 false 后应该提前停止，期望遍历 2 个，实际 %d: %v", len(visited), visited)
+	}
+}
+
+func TestOrderedMapDelete(t *testing.T) {
+	m := New[string, interface{}]()
+	m.Set("a", 1)
+	m.Set("b", 2)
+	m.Delete("a")
+
+	if _, ok := m.Get("a"); ok {
+		t.Fatalf("删除后 a 不应该还能取到")
+	}
+	if m.Len() != 1 {
+		t.Fatalf("期望 Len()=1，实际 %d", m.Len())
+	}
+	m.Delete("not-there") // no-op，不应该 panic
+}
+
+func TestOrderedMapLenOnNilIsZero(t *testing.T) {
+	var m *OrderedMap[string, interface{}]
+	if m.Len() != 0 {
+		t.Fatalf("nil map 的 Len() 应该是 0")
+	}
+	if _, ok := m.Get("a"); ok {
+		t.Fatalf("nil map 的 Get() 应该返回 ok=false")
+	}
+}
+
+func TestOrderedMapMarshalJSONPreservesKeyOrder(t *testing.T) {
+	m := New[string, interface{}]()
+	m.Set("z", 1.0)
+	m.Set("a", 2.0)
+
+	data, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON 失败: %v", err)
+	}
+	if got, want := string(data), `{"z":1,"a":2}`; got != want {
+		t.Fatalf("期望按插入顺序序列化成 %s，实际 %s", want, got)
+	}
+}
+
+func TestOrderedMapUnmarshalJSONPreservesSourceOrder(t *testing.T) {
+	m := New[string, interface{}]()
+	if err := m.UnmarshalJSON([]byte(`{"z":1,"a":2,"m":3}`)); err != nil {
+		t.Fatalf("UnmarshalJSON 失败: %v", err)
+	}
+
+	var keys []string
+	m.Range(func(key string, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	want := []string{"z", "a", "m"}
+	for i, k := range want {
+		if keys[i] != k {
+			t.Fatalf("期望按 JSON 源文本顺序重建 %v，实际 %v", want, keys)
+		}
+	}
+}
+
+func TestOrderedMapRoundTripJSON(t *testing.T) {
+	m := New[string, interface{}]()
+	m.Set("first", "a")
+	m.Set("second", "b")
+
+	data, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON 失败: %v", err)
+	}
+
+	restored := New[string, interface{}]()
+	if err := restored.UnmarshalJSON(data); err != nil {
+		t.Fatalf("UnmarshalJSON 失败: %v", err)
+	}
+	if v, _ := restored.Get("first"); v != "a" {
+		t.Fatalf("还原后 first 应该是 a，实际 %v", v)
+	}
+	if v, _ := restored.Get("second"); v != "b" {
+		t.Fatalf("还原后 second 应该是 b，实际 %v", v)
+	}
+}
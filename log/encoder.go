@@ -0,0 +1,53 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Encoder 把一条 Entry 渲染成准备写盘/写终端的字节。
+type Encoder interface {
+	Encode(entry Entry) []byte
+}
+
+// TextEncoder 渲染成 "[time] [LEVEL] msg key=value key=value\n"，人眼直接读的格式，
+// 和改造前 "[time] [LEVEL] msg" 的观感保持一致，只是尾部多了结构化字段。
+type TextEncoder struct{}
+
+func (TextEncoder) Encode(entry Entry) []byte {
+	var b strings.Builder
+	b.WriteByte('[')
+	b.WriteString(entry.Time.Format(time.RFC3339))
+	b.WriteString("] [")
+	b.WriteString(entry.Level.String())
+	b.WriteString("] ")
+	b.WriteString(entry.Message)
+	for _, f := range entry.Fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		fmt.Fprintf(&b, "%v", f.Value)
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// JSONEncoder 渲染成单行 JSON，方便喂给日志采集系统。
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(entry Entry) []byte {
+	record := make(map[string]interface{}, len(entry.Fields)+3)
+	record["time"] = entry.Time.Format(time.RFC3339)
+	record["level"] = entry.Level.String()
+	record["msg"] = entry.Message
+	for _, f := range entry.Fields {
+		record[f.Key] = f.Value
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return []byte(fmt.Sprintf(`{"time":%q,"level":"ERROR","msg":"日志编码失败: %s"}`+"\n", entry.Time.Format(time.RFC3339), err.Error()))
+	}
+	return append(line, '\n')
+}
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultMaxBytes 是单个日志文件允许长到的大小，超过后触发按大小滚动。
+const defaultMaxBytes = 64 * 1024 * 1024
+
+// defaultRolloverCheckInterval 是后台 goroutine 检查日期是否翻篇的轮询间隔；
+// 写日志时也会顺带检查一次，这个 goroutine 只是兜底没有日志流量时也能按时滚动。
+const defaultRolloverCheckInterval = time.Minute
+
+// FileSink 把日志写到 dir 下按日期命名的文件里，单文件超过 maxBytes 或者日期
+// 变化时滚动：同一天内按体积滚动时旧文件先改名成带时间戳的归档名，日期变化时旧文件
+// 保持原名；两种情况都异步 gzip 压缩旧文件，不阻塞当前写入。
+type FileSink struct {
+	dir      string
+	encoder  Encoder
+	maxBytes int64
+
+	mu          sync.Mutex
+	file        *os.File
+	currentDate string
+	currentSize int64
+
+	stop chan struct{}
+}
+
+// NewFileSink 创建一个 FileSink 并打开当天的日志文件，同时启动后台滚动检查。
+func NewFileSink(dir string, encoder Encoder) (*FileSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+	s := &FileSink{
+		dir:      dir,
+		encoder:  encoder,
+		maxBytes: defaultMaxBytes,
+		stop:     make(chan struct{}),
+	}
+	if err := s.openForDate(currentDate()); err != nil {
+		return nil, err
+	}
+	go s.rolloverLoop()
+	return s, nil
+}
+
+func currentDate() string {
+	return time.Now().Format("2006-01-02")
+}
+
+// Close 停止后台滚动检查并关闭当前文件。
+func (s *FileSink) Close() error {
+	close(s.stop)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+func (s *FileSink) Write(entry Entry) {
+	line := s.encoder.Encode(entry)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	date := entry.Time.Format("2006-01-02")
+	if date != s.currentDate {
+		s.rotateLocked(date)
+	} else if s.currentSize+int64(len(line)) > s.maxBytes {
+		s.rotateLocked(date)
+	}
+
+	n, err := s.file.Write(line)
+	if err != nil {
+		return
+	}
+	s.currentSize += int64(n)
+}
+
+// rolloverLoop 定期检查日期是否翻篇，避免长时间没有日志流量时文件停在旧日期。
+func (s *FileSink) rolloverLoop() {
+	ticker := time.NewTicker(defaultRolloverCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			date := currentDate()
+			s.mu.Lock()
+			if date != s.currentDate {
+				s.rotateLocked(date)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// rotateLocked 关闭当前文件、异步 gzip 归档，再按 date 打开一个新文件。调用方必须持有 s.mu。
+func (s *FileSink) rotateLocked(date string) {
+	oldFile := s.file
+	oldPath := ""
+	if oldFile != nil {
+		oldPath = oldFile.Name()
+	}
+	targetPath := filepath.Join(s.dir, date+".log")
+
+	if oldFile != nil && oldPath == targetPath {
+		// 同一天内因体积触发的滚动，新旧文件名相同，先把旧文件挪到带时间戳的
+		// 归档名上，不然打开的新文件会和还没归档完的旧文件共享同一个路径。
+		oldFile.Close()
+		archivedPath := fmt.Sprintf("%s.%d", oldPath, time.Now().UnixNano())
+		if err := os.Rename(oldPath, archivedPath); err == nil {
+			go archiveFileAt(archivedPath)
+		}
+		if err := s.openForDate(date); err != nil {
+			return
+		}
+		return
+	}
+
+	if err := s.openForDate(date); err != nil {
+		// 打开新文件失败就继续用旧文件，总比日志全丢了强。
+		return
+	}
+	if oldFile != nil {
+		go archiveFile(oldFile, oldPath)
+	}
+}
+
+// openForDate 打开（或续写）给定日期对应的日志文件，调用方必须持有 s.mu。
+func (s *FileSink) openForDate(date string) error {
+	path := filepath.Join(s.dir, date+".log")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	info, err := f.Stat()
+	size := int64(0)
+	if err == nil {
+		size = info.Size()
+	}
+	s.file = f
+	s.currentDate = date
+	s.currentSize = size
+	return nil
+}
+
+// archiveFile 关闭一个滚动下来的文件句柄再 gzip 压缩它。
+func archiveFile(f *os.File, path string) {
+	f.Close()
+	archiveFileAt(path)
+}
+
+// archiveFileAt 把 path 指向的日志文件 gzip 压缩成 <path>.gz 再删除原文件。
+func archiveFileAt(path string) {
+	if path == "" {
+		return
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return
+	}
+	defer dst.Close()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		return
+	}
+	if err := gz.Close(); err != nil {
+		return
+	}
+	os.Remove(path)
+}
@@ -1,62 +1,112 @@
+// Package log 提供一个轻量的结构化日志器：分级别（Debug/Info/Warn/Error）、
+// 携带 key/value 字段、可同时输出到多个 Sink（文件、标准输出、内存环形缓冲）。
 package log
 
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"time"
-)
+import "time"
 
-type DailyLogger struct {
-	logDir      string
-	currentDate string
-}
+// Level 是日志级别，数值越大越严重，Logger.level 以上的日志才会真正写出去。
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
 
-func New(logDir string) *DailyLogger {
-	_ = os.MkdirAll(logDir, 0755) // 创建日志目录
-	return &DailyLogger{
-		logDir:      logDir,
-		currentDate: getCurrentDate(),
+// String 返回级别的大写文本形式，编码器用它写出级别。
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "DEBUG"
+	case Info:
+		return "INFO"
+	case Warn:
+		return "WARN"
+	case Error:
+		return "ERROR"
+	default:
+		return "UNKNOWN"
 	}
 }
 
-func getCurrentDate() string {
-	return time.Now().Format("2006-01-02")
+// Field 是一条日志附带的 key/value 上下文，比如 actionID、sessionID、循环次数。
+type Field struct {
+	Key   string
+	Value interface{}
 }
 
-func (l *DailyLogger) getLogFile(logType string) string {
-	currentDate := getCurrentDate()
-	filename := currentDate
-	if logType == "ERROR" {
-		filename += ".error.log"
-	} else {
-		filename += ".log"
+func String(key, value string) Field  { return Field{Key: key, Value: value} }
+func Int(key string, value int) Field { return Field{Key: key, Value: value} }
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: "error", Value: nil}
 	}
-	return filepath.Join(l.logDir, filename)
+	return Field{Key: "error", Value: err.Error()}
 }
 
-func (l *DailyLogger) writeLog(logType, message string) {
-	logFile := l.getLogFile(logType)
-	timestamp := time.Now().Format(time.RFC3339)
-	logMessage := fmt.Sprintf("[%s] [%s] %s\n", timestamp, logType, message)
+// Entry 是一条完整的日志记录，交给 Sink 去决定怎么落地。
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+}
 
-	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return
-	}
-	defer f.Close()
+// DailyLogger 是按日期滚动的结构化日志器：level 以下的日志直接丢弃，其余的
+// 连同 base 字段（由 With 固定下来的上下文）一起交给每个 sink 写出去。
+// 名字沿用自早先未分级时的实现，避免打乱既有的 log.New / Logger 调用方式。
+type DailyLogger struct {
+	sinks []Sink
+	level Level
+	base  []Field
+}
 
-	_, _ = f.WriteString(logMessage)
+// New 创建一个 DailyLogger，默认级别是 Info，sinks 为空时日志会被静默丢弃——
+// 调用方通常紧接着用 AddSink 挂上 NewFileSink/NewStdoutSink/NewRingSink。
+func New(sinks ...Sink) *DailyLogger {
+	return &DailyLogger{sinks: sinks, level: Info}
 }
 
-func (l *DailyLogger) Info(message string) {
-	l.writeLog("INFO", message)
+// SetLevel 调整最低输出级别，返回自身方便链式调用。
+func (l *DailyLogger) SetLevel(level Level) *DailyLogger {
+	l.level = level
+	return l
 }
 
-func (l *DailyLogger) Warn(message string) {
-	l.writeLog("WARN", message)
+// AddSink 追加一个 sink。
+func (l *DailyLogger) AddSink(sink Sink) {
+	l.sinks = append(l.sinks, sink)
 }
 
-func (l *DailyLogger) Error(message string) {
-	l.writeLog("ERROR", message)
+// With 返回一个共享 sinks、但在每条日志前都带上给定字段的子 logger，
+// 用来固定住一次请求/任务生命周期内的 actionID、sessionID 之类的上下文。
+func (l *DailyLogger) With(fields ...Field) *DailyLogger {
+	merged := make([]Field, 0, len(l.base)+len(fields))
+	merged = append(merged, l.base...)
+	merged = append(merged, fields...)
+	return &DailyLogger{sinks: l.sinks, level: l.level, base: merged}
 }
+
+func (l *DailyLogger) log(level Level, message string, fields []Field) {
+	if level < l.level || len(l.sinks) == 0 {
+		return
+	}
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: message,
+		Fields:  append(append([]Field{}, l.base...), fields...),
+	}
+	for _, sink := range l.sinks {
+		sink.Write(entry)
+	}
+}
+
+func (l *DailyLogger) Debug(message string, fields ...Field) { l.log(Debug, message, fields) }
+func (l *DailyLogger) Info(message string, fields ...Field)  { l.log(Info, message, fields) }
+func (l *DailyLogger) Warn(message string, fields ...Field)  { l.log(Warn, message, fields) }
+func (l *DailyLogger) Error(message string, fields ...Field) { l.log(Error, message, fields) }
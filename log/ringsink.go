@@ -0,0 +1,119 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// RingSink 把最近的日志留在内存里一个环形缓冲区，供 TailHandler 通过 SSE 下发：
+// 新连接先收到缓冲区里已有的内容，随后实时收到新写入的日志。
+type RingSink struct {
+	encoder Encoder
+	size    int
+
+	mu          sync.Mutex
+	buf         []Entry
+	start       int // buf 里最旧一条的下标
+	count       int
+	subscribers map[chan Entry]struct{}
+}
+
+// NewRingSink 创建一个最多保留 size 条日志的 RingSink。
+func NewRingSink(size int) *RingSink {
+	return &RingSink{
+		encoder:     TextEncoder{},
+		size:        size,
+		buf:         make([]Entry, size),
+		subscribers: make(map[chan Entry]struct{}),
+	}
+}
+
+func (s *RingSink) Write(entry Entry) {
+	s.mu.Lock()
+	idx := (s.start + s.count) % s.size
+	s.buf[idx] = entry
+	if s.count < s.size {
+		s.count++
+	} else {
+		s.start = (s.start + 1) % s.size
+	}
+	subs := make([]chan Entry, 0, len(s.subscribers))
+	for ch := range s.subscribers {
+		subs = append(subs, ch)
+	}
+	s.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- entry:
+		default:
+			// 订阅者跟不上就丢弃这条，不让慢消费者拖慢写日志。
+		}
+	}
+}
+
+// Recent 返回目前缓冲区里最旧到最新的全部日志。
+func (s *RingSink) Recent() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Entry, s.count)
+	for i := 0; i < s.count; i++ {
+		out[i] = s.buf[(s.start+i)%s.size]
+	}
+	return out
+}
+
+// subscribe 注册一个接收后续日志的 channel，返回取消订阅的函数。
+func (s *RingSink) subscribe() (chan Entry, func()) {
+	ch := make(chan Entry, 16)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+		close(ch)
+	}
+}
+
+// encodeLine 渲染出不带结尾换行的单行文本，方便拼进一帧只占一行 data: 的 SSE 事件。
+func (s *RingSink) encodeLine(entry Entry) string {
+	return strings.TrimRight(string(s.encoder.Encode(entry)), "\n")
+}
+
+// TailHandler 是 /logs/tail 的 http.HandlerFunc：先把缓冲区里已有的日志推给客户端，
+// 再持续把新日志以 SSE 帧推送过去，直到客户端断开连接。
+func (s *RingSink) TailHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming 不支持", http.StatusInternalServerError)
+		return
+	}
+
+	ch, unsubscribe := s.subscribe()
+	defer unsubscribe()
+
+	for _, entry := range s.Recent() {
+		fmt.Fprintf(w, "data: %s\n\n", s.encodeLine(entry))
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-ch:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", s.encodeLine(entry))
+			flusher.Flush()
+		}
+	}
+}
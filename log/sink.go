@@ -0,0 +1,25 @@
+package log
+
+import (
+	"os"
+)
+
+// Sink 接收已经组装好的 Entry 并决定怎么落地，DailyLogger 会把每条日志分别
+// 交给所有挂载的 sink，互不影响（某个 sink 写失败不会影响其它 sink）。
+type Sink interface {
+	Write(entry Entry)
+}
+
+// StdoutSink 把日志用 TextEncoder 写到标准输出，主要用于本地调试。
+type StdoutSink struct {
+	Encoder Encoder
+}
+
+// NewStdoutSink 创建一个用 TextEncoder 的 StdoutSink。
+func NewStdoutSink() *StdoutSink {
+	return &StdoutSink{Encoder: TextEncoder{}}
+}
+
+func (s *StdoutSink) Write(entry Entry) {
+	os.Stdout.Write(s.Encoder.Encode(entry))
+}
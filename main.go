@@ -1,16 +1,23 @@
 package main
 
 import (
+	"books-vector-api/auth"
+	"books-vector-api/bridge"
+	"books-vector-api/export"
 	"books-vector-api/log"
+	"books-vector-api/mw"
 	"books-vector-api/outbox"
 	"books-vector-api/vector"
 	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	// "go.mongodb.org/mongo-driver/bson/primitive"
 
@@ -36,14 +43,96 @@ type Config struct {
 	ChromaDBURL      string `json:"ChromaDBURL"`
 	ChromDBTenants   string `json:"ChromDBTenants"`
 	ChromaDBDatabase string `json:"ChromaDBDatabase"`
+
+	// VectorBackend 选择 addToChromaDB/vectorSearch 等调用落到哪个 vector.Store 实现上：
+	// "chroma"（默认）、"qdrant" 或 "memory"（本地开发用，不持久化）。
+	VectorBackend string `json:"VectorBackend"`
+	QdrantURL     string `json:"QdrantURL"`
+
+	// EmbeddingProvider 选择 outbox.Embedder 的具体实现："ollama"（默认，使用 OllamaURL）、
+	// "openai"（使用 EmbeddingURL，按 OpenAI /v1/embeddings 格式调用）或 "provider_uri"（复用 ModelProviderURI）。
+	EmbeddingProvider  string `json:"EmbeddingProvider"`
+	EmbeddingURL       string `json:"EmbeddingURL"`
+	EmbeddingModel     string `json:"EmbeddingModel"`
+	EmbeddingBatchSize int    `json:"EmbeddingBatchSize"`
+	EmbeddingDim       int    `json:"EmbeddingDim"`
+
+	// JWTSecret 用来签发/校验 /auth/login 颁发的 token，保护除 /auth/ 外的所有接口。
+	JWTSecret string `json:"JWTSecret"`
+	// AuthDBPath 是 auth.UserStore 使用的 SQLite 文件路径。
+	AuthDBPath string `json:"AuthDBPath"`
+	// TaskOutboxDBPath 是 outbox.EventStore 持久化 /task、/planning 事件的 SQLite 文件路径。
+	TaskOutboxDBPath string `json:"TaskOutboxDBPath"`
+
+	// SessionBackend 选择 outbox.Session 的持久化实现："sqlite"（默认，跨重启保留 ask/answer
+	// 历史）或 "memory"（本地开发，不持久化）。
+	SessionBackend string `json:"SessionBackend"`
+	// SessionDBPath 是 SessionBackend 为 sqlite 时使用的文件路径，默认 "./sessions.db"。
+	SessionDBPath string `json:"SessionDBPath"`
+
+	// ActorKeyDir 是 /actors/{name} 的 RSA keypair 持久化目录，默认 "./actors"。
+	ActorKeyDir string `json:"ActorKeyDir"`
+	// PublicBaseURL 是本实例对外的协议+host（例如 "https://wenko.example"），用于拼 actor
+	// IRI；留空则按收到请求的 r.Host 现拼，适合本地开发。
+	PublicBaseURL string `json:"PublicBaseURL"`
+
+	// DeliveryDBPath 是 outbox.DeliveryStore 持久化待投递事件的 SQLite 文件路径，
+	// 默认 "./delivery.db"。
+	DeliveryDBPath string `json:"DeliveryDBPath"`
+	// DeliveryWebhookURL 非空时，Session.AddEntry 产生的 "ask" 类型事件会投递给这个
+	// webhook；DeliveryWebhookSecret 用来给投递请求算 HMAC-SHA256 签名。
+	DeliveryWebhookURL    string `json:"DeliveryWebhookURL"`
+	DeliveryWebhookSecret string `json:"DeliveryWebhookSecret"`
+
+	// MemoryCollection 是对话记忆专用的 Chroma collection 名称，默认 "memory"。
+	MemoryCollection string `json:"MemoryCollection"`
+	// MemoryBackend 选择 memory.Store 实现："chroma"（默认，跨重启持久化）或 "memory"（本地开发）。
+	MemoryBackend string `json:"MemoryBackend"`
+	// MemoryTopK 是召回历史轮次的条数，默认 5。
+	MemoryTopK int `json:"MemoryTopK"`
+	// MemorySimilarityThreshold 是召回的最大 distance（越小越相似），默认 0.5。
+	MemorySimilarityThreshold float32 `json:"MemorySimilarityThreshold"`
+	// MemoryTokenBudget 是拼进上下文的历史轮次近似字符数上限，默认 2000。
+	MemoryTokenBudget int `json:"MemoryTokenBudget"`
+
+	// BridgeConfigPath 非空时启用 bridge.Manager，按该路径指向的 YAML 文件里的绑定关系，
+	// 把本地 Session 和下面配置好的外部聊天网络接起来。
+	BridgeConfigPath string `json:"BridgeConfigPath"`
+	// TelegramBotToken 非空时启用 Telegram 适配器。
+	TelegramBotToken string `json:"TelegramBotToken"`
+	// MatrixHomeserverURL/MatrixUserID/MatrixAccessToken 均非空时启用 Matrix 适配器。
+	MatrixHomeserverURL string `json:"MatrixHomeserverURL"`
+	MatrixUserID        string `json:"MatrixUserID"`
+	MatrixAccessToken   string `json:"MatrixAccessToken"`
+	// IRCServer 非空时启用 IRC 适配器，IRCChannels 是加入的频道列表。
+	IRCServer   string   `json:"IRCServer"`
+	IRCPort     int      `json:"IRCPort"`
+	IRCNick     string   `json:"IRCNick"`
+	IRCChannels []string `json:"IRCChannels"`
 }
 
 var config Config
 var Logger *log.DailyLogger
+var LogTail *log.RingSink
+var Store vector.Store
+var TextEmbedder outbox.Embedder
+var Users *auth.UserStore
+
+// bmIndex 是 content 的 BM25 倒排索引，在 addToChromaDB/deleteRecord 里和向量记录保持同步，
+// 供 /search 的混合检索使用。
+var bmIndex = vector.NewBM25Index()
+
+// logTailRingSize 是 /logs/tail 能回看的最近日志条数。
+const logTailRingSize = 500
 
 func init() {
-	// 使用相对路径
-	Logger = log.New("./logs")
+	// 使用相对路径：文件按天滚动落盘 + 标准输出便于本地调试 + 内存环形缓冲供 /logs/tail 实时查看。
+	fileSink, err := log.NewFileSink("./logs", log.TextEncoder{})
+	if err != nil {
+		panic(fmt.Sprintf("初始化日志文件失败: %v", err))
+	}
+	LogTail = log.NewRingSink(logTailRingSize)
+	Logger = log.New(fileSink, log.NewStdoutSink(), LogTail)
 
 	file, err := os.Open("config.json")
 	if err != nil {
@@ -59,6 +148,69 @@ func init() {
 	outbox.InitModelProvider(config.ModelProviderURI, config.ModelProviderModel, config.ModelProviderAPIKey)
 	outbox.Init(Logger)
 
+	if err := outbox.InitTaskOutbox(config.TaskOutboxDBPath); err != nil {
+		panic(fmt.Sprintf("初始化任务 outbox 失败: %v", err))
+	}
+
+	sessionStore, err := newSessionStore()
+	if err != nil {
+		panic(fmt.Sprintf("初始化会话存储失败: %v", err))
+	}
+	outbox.InitSessionStore(sessionStore)
+	if err := outbox.ReplayPendingTasks(); err != nil {
+		fmt.Println("重放未完成任务失败:", err)
+	}
+	// maxAttempts=3，每 5 秒轮询一次：追上那些卡在 answer/interrupt 的事件。
+	go outbox.RunEventWorker(context.Background(), 5*time.Second, 3, outbox.DefaultEventHandlers())
+
+	outbox.InitActivityPub(config.ActorKeyDir)
+	outbox.PublicBaseURL = config.PublicBaseURL
+
+	deliveryDBPath := config.DeliveryDBPath
+	if deliveryDBPath == "" {
+		deliveryDBPath = "./delivery.db"
+	}
+	deliveryStore, err := outbox.NewDeliveryStore(deliveryDBPath)
+	if err != nil {
+		panic(fmt.Sprintf("初始化投递 outbox 失败: %v", err))
+	}
+	delivery := outbox.NewDeliveryOutbox(deliveryStore)
+	if config.DeliveryWebhookURL != "" {
+		delivery.RegisterSink("webhook", outbox.NewWebhookSink(config.DeliveryWebhookURL, config.DeliveryWebhookSecret))
+		delivery.Route("ask", "webhook")
+	}
+	outbox.InitDeliveryOutbox(delivery)
+	// perSinkLimit=4、maxAttempts=8（重试间隔 1s、2s……64s 逐次翻倍，第 8 次失败即进入死信）、每 2 秒轮询一次。
+	go outbox.NewDeliveryWorkerPool(delivery, 4, 8, 2*time.Second).Run(context.Background())
+
+	bridge.Init(Logger)
+	if config.BridgeConfigPath != "" {
+		bridgeCfg, err := bridge.LoadConfig(config.BridgeConfigPath)
+		if err != nil {
+			panic(fmt.Sprintf("加载 bridge 配置失败: %v", err))
+		}
+		var bridges []bridge.Bridge
+		if config.TelegramBotToken != "" {
+			tg, err := bridge.NewTelegramBridge(config.TelegramBotToken)
+			if err != nil {
+				panic(fmt.Sprintf("初始化 telegram 桥接失败: %v", err))
+			}
+			bridges = append(bridges, tg)
+		}
+		if config.MatrixHomeserverURL != "" && config.MatrixUserID != "" && config.MatrixAccessToken != "" {
+			mx, err := bridge.NewMatrixBridge(config.MatrixHomeserverURL, config.MatrixUserID, config.MatrixAccessToken)
+			if err != nil {
+				panic(fmt.Sprintf("初始化 matrix 桥接失败: %v", err))
+			}
+			bridges = append(bridges, mx)
+		}
+		if config.IRCServer != "" {
+			bridges = append(bridges, bridge.NewIRCBridge(config.IRCServer, config.IRCPort, config.IRCNick, config.IRCChannels))
+		}
+		manager := bridge.NewManager(bridgeCfg, bridges...)
+		go manager.Run(context.Background(), outbox.Sessions)
+	}
+
 	// 检查租户 addTenant 是否存在
 	fmt.Println("🌍正在添加租户...")
 	if err := addTenants(); err != nil {
@@ -72,11 +224,98 @@ func init() {
 	if err := addEmbeddingCollection(); err != nil {
 		panic(fmt.Sprintf("添加Embedding集合失败: %v", err))
 	}
+	fmt.Println("🌍正在添加记忆集合...")
+	if err := addMemoryCollection(); err != nil {
+		panic(fmt.Sprintf("添加记忆集合失败: %v", err))
+	}
+
+	Store = newVectorStore()
+	TextEmbedder = newEmbedder()
+	MemStore = newMemoryStore()
+
+	if err := rebuildBM25Index(); err != nil {
+		fmt.Println("重建 BM25 索引失败:", err)
+	}
+
+	outbox.VectorSearchFunc = vectorSearchTool
+	outbox.ExportAllDataFunc = func() error {
+		_, _, err := runExport(export.FormatCSV, "recursive", "")
+		return err
+	}
+	outbox.RecallMemoryFunc = recallContext
+	outbox.RememberTurnFunc = rememberTurn
+
+	users, err := auth.NewUserStore(config.AuthDBPath)
+	if err != nil {
+		panic(fmt.Sprintf("初始化用户数据库失败: %v", err))
+	}
+	Users = users
 }
 
-// Ollama响应结构
-type OllamaResponse struct {
-	Embedding []float32 `json:"embedding"`
+// newEmbedder 根据 config.EmbeddingProvider 选择 Embedder 实现的请求地址。
+func newEmbedder() outbox.Embedder {
+	embedderURL := config.EmbeddingURL
+	if config.EmbeddingProvider == "" || config.EmbeddingProvider == "ollama" {
+		embedderURL = config.OllamaURL
+	}
+	if config.EmbeddingProvider == "provider_uri" {
+		embedderURL = config.ModelProviderURI
+	}
+	return outbox.NewEmbedder(outbox.EmbedderConfig{
+		Provider:  config.EmbeddingProvider,
+		URL:       embedderURL,
+		Model:     config.EmbeddingModel,
+		APIKey:    config.ModelProviderAPIKey,
+		BatchSize: config.EmbeddingBatchSize,
+		Dim:       config.EmbeddingDim,
+	})
+}
+
+// newVectorStore 根据 config.VectorBackend 选择具体的 vector.Store 实现。
+func newVectorStore() vector.Store {
+	switch config.VectorBackend {
+	case "qdrant":
+		fmt.Println("🌍向量存储后端: qdrant")
+		return vector.NewQdrantStore(config.QdrantURL, config.Collection)
+	case "memory":
+		fmt.Println("🌍向量存储后端: memory")
+		return vector.NewMemoryStore()
+	default:
+		fmt.Println("🌍向量存储后端: chroma")
+		return vector.NewChromaStore(config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase, CollectionId)
+	}
+}
+
+// rebuildBM25Index 分页读出 Store 里已有的全部记录，重建 bmIndex。bmIndex 只在进程内存里，
+// 之前只在 addToChromaDB/deleteRecord 里增量维护，进程重启后是空的，混合检索在那些记录
+// 被重新写入之前会一直漏掉它们——这里在 Store 就绪后跑一遍启动时重建，补上这段空档。
+func rebuildBM25Index() error {
+	const pageSize = 100
+	offset := 0
+	for {
+		result, err := Store.List(pageSize, offset)
+		if err != nil {
+			return fmt.Errorf("分页读取记录失败（offset=%d）: %w", offset, err)
+		}
+		if len(result.IDs) == 0 {
+			break
+		}
+		for i, id := range result.IDs {
+			if i >= len(result.Metadatas) {
+				continue
+			}
+			content, _ := result.Metadatas[i]["content"].(string)
+			if content == "" {
+				continue
+			}
+			bmIndex.AddDocument(id, content)
+		}
+		offset += len(result.IDs)
+		if len(result.IDs) < pageSize {
+			break
+		}
+	}
+	return nil
 }
 
 func addTenants() error {
@@ -141,38 +380,62 @@ func addDatabases() error {
 
 var CollectionId string
 
+// MemoryCollectionId 是 memory 子系统专用的 Chroma collection id，和 CollectionId
+// 分开存放，这样 /search、/documents 等遍历接口不会被历史对话记录污染。
+var MemoryCollectionId string
+
 func addEmbeddingCollection() error {
-	// 检查用于 embedding 的集合是否存在 /api/v2/tenants/{tenant}/databases/{database}/collections get
+	id, err := ensureCollection(config.Collection)
+	if err != nil {
+		return err
+	}
+	CollectionId = id
+	return nil
+}
+
+func addMemoryCollection() error {
+	name := config.MemoryCollection
+	if name == "" {
+		name = "memory"
+	}
+	id, err := ensureCollection(name)
+	if err != nil {
+		return err
+	}
+	MemoryCollectionId = id
+	return nil
+}
+
+// ensureCollection 确保 Chroma 里存在名为 name 的 collection，返回其 id，
+// 不存在则创建。供 addEmbeddingCollection/addMemoryCollection 共用。
+func ensureCollection(name string) (string, error) {
+	// 检查集合是否存在 /api/v2/tenants/{tenant}/databases/{database}/collections get
 	existsURL := fmt.Sprintf("%s/tenants/%s/databases/%s/collections", config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase)
 	existsResp, err := http.Get(existsURL)
 	if err != nil {
-		return fmt.Errorf("failed to check collection existence: %v", err)
+		return "", fmt.Errorf("failed to check collection existence: %v", err)
 	}
 	defer existsResp.Body.Close()
 	if existsResp.StatusCode == http.StatusOK {
-		// 遍历 existsResp.Body，匹配 name == config.Collection 的集合，将其 id 赋值到 CollectionId
 		var existsRespBody []struct {
 			Name string `json:"name"`
 			ID   string `json:"id"`
 		}
 		json.NewDecoder(existsResp.Body).Decode(&existsRespBody)
 		for _, collection := range existsRespBody {
-			if collection.Name == config.Collection {
-				CollectionId = collection.ID
+			if collection.Name == name {
+				fmt.Println("Embedding 集合已存在: ", collection.ID)
+				return collection.ID, nil
 			}
 		}
-		if CollectionId != "" {
-			fmt.Println("Embedding 集合已存在: ", CollectionId)
-			return nil
-		}
 	}
-	// 创建用于 embedding 的集合 /api/v2/tenants/{tenant}/databases/{database}/collections post
+	// 创建集合 /api/v2/tenants/{tenant}/databases/{database}/collections post
 	createURL := fmt.Sprintf("%s/tenants/%s/databases/%s/collections", config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase)
 	payload := struct {
 		Name      string            `json:"name"`
 		Metadatas map[string]string `json:"metadatas"`
 	}{
-		Name: config.Collection,
+		Name: name,
 		Metadatas: map[string]string{
 			"hnsw:space": "ip", // "cosine" "12" "ip"
 		},
@@ -180,63 +443,41 @@ func addEmbeddingCollection() error {
 	body, _ := json.Marshal(payload)
 	resp, err := http.Post(createURL, "application/json", bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create collection: %v", err)
+		return "", fmt.Errorf("failed to create collection: %v", err)
 	}
 	defer resp.Body.Close()
-	// 将 resp.Body 的 id 赋值到 CreationId
 	var respBody struct {
 		ID   string `json:"id"`
 		Name string `json:"name"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return fmt.Errorf("failed to decode response body: %v", err)
+		return "", fmt.Errorf("failed to decode response body: %v", err)
 	}
-	CollectionId = respBody.ID
-	fmt.Println("Collection created: ", CollectionId)
-	return nil
+	fmt.Println("Collection created: ", respBody.ID)
+	return respBody.ID, nil
 }
 
-func addToChromaDB(id string, embedding []float32, texts []WeightedText) (string, error) {
-	// fmt.Println("Adding to ChromaDB...", embedding)
+// addToChromaDB 写入一条记录，tenantID 非空时写入 tenant_id 元数据，供 vectorSearch 等
+// 按租户过滤，实现多用户共享同一个 Chroma collection 时的业务级隔离。
+func addToChromaDB(id string, embedding []float32, texts []WeightedText, tenantID string) (string, error) {
 	var content string
 	for _, text := range texts {
 		content += fmt.Sprintf("%s-(weight-assign:%f)-$-$", text.Text, text.Weight)
 	}
-	// 构造请求体
-	payload := struct {
-		Ids        []string            `json:"ids"`
-		Embeddings [][]float32         `json:"embeddings"`
-		Metadatas  []map[string]string `json:"metadatas,omitempty"`
-	}{
-		Ids:        []string{id},
-		Embeddings: [][]float32{embedding},
-		Metadatas: []map[string]string{
-			{"content": content},
-		},
+	metadata := map[string]string{"content": content}
+	if tenantID != "" {
+		metadata["tenant_id"] = tenantID
 	}
-	// fmt.Println("payload:", payload)
-	body, _ := json.Marshal(payload)
-	// /api/v2/tenants/{tenant}/databases/{database}/collections/{collection_id}/add post
-	url := fmt.Sprintf("%s/tenants/%s/databases/%s/collections/%s/add", config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase, CollectionId)
-	// fmt.Println("url:", url)
-	// url := fmt.Sprintf("%s/collections/%s/add", config.ChromaDBURL, collectionName)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
-	if err != nil {
+	if err := Store.Upsert(id, embedding, metadata); err != nil {
 		fmt.Println("Error:", err)
 		return "", err
 	}
-	defer resp.Body.Close()
-	fmt.Println("Response Status:", resp.Status)
-	// 如果 resp.StatusCode 等于 201 或者 200 返回 id
-	if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK {
-		return id, nil
-	}
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	return "", fmt.Errorf("failed to add to chromadb: %s", string(bodyBytes))
+	bmIndex.AddDocument(id, content)
+	return id, nil
 }
 
-func generateAndStore(texts []WeightedText) (string, error) {
-	embedding, err := generateWeightedEmbedding(texts)
+func generateAndStore(ctx context.Context, texts []WeightedText, tenantID string) (string, error) {
+	embedding, err := generateWeightedEmbedding(ctx, texts)
 	if err != nil {
 		return "", err
 	}
@@ -244,7 +485,7 @@ func generateAndStore(texts []WeightedText) (string, error) {
 	// id 使用 UUIDv4 生成
 	id := strings.ReplaceAll(uuid.New().String(), "-", "")
 	// fmt.Println("Adding to ChromaDB...", response)
-	id, err = addToChromaDB(id, embedding, texts)
+	id, err = addToChromaDB(id, embedding, texts, tenantID)
 	fmt.Println(id, err)
 	if err != nil {
 		return "failed to add to chromadb:", err
@@ -254,136 +495,154 @@ func generateAndStore(texts []WeightedText) (string, error) {
 
 // 删除记录
 func deleteRecord(recordID string) (string, error) {
-	// /api/v2/tenants/{tenant}/databases/{database}/collections/{collection_id}/delete post
-	url := fmt.Sprintf("%s/tenants/%s/databases/%s/collections/%s/delete", config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase, CollectionId)
-	payload := struct {
-		IDs []string `json:"ids"`
-	}{
-		IDs: []string{recordID},
-	}
-	body, err := json.Marshal(payload)
-	if err != nil {
+	if err := Store.Delete([]string{recordID}); err != nil {
 		return "", err
 	}
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	bmIndex.RemoveDocument(recordID)
+	return recordID, nil
+}
+
+// 向量相似性检索，n 为召回数量，filter 为 Chroma 风格的 where 子句（可为空）。
+func vectorSearch(queryVector []float32, n int, filter map[string]interface{}) ([]map[string]interface{}, error) {
+	Logger.Info("向量检索")
+	results, err := Store.Query(queryVector, n, filter)
 	if err != nil {
-		return "", err
+		fmt.Println("Error:", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP request failed with status code %d", resp.StatusCode)
+
+	allResults := make([]map[string]interface{}, len(results))
+	for i, result := range results {
+		allResults[i] = map[string]interface{}{
+			"id":        result.ID,
+			"metadata":  result.Metadata,
+			"embedding": result.Embedding,
+		}
 	}
-	return recordID, nil
+	Logger.Info("向量检索结果: " + fmt.Sprintf("%v", allResults))
+	return allResults, nil
 }
 
-type VectorSearchResponse struct {
-	IDs        [][]string                 `json:"ids"`
-	Embeddings [][]float32                `json:"embeddings"`
-	Documents  [][]interface{}            `json:"documents"`
-	Metadatas  [][]map[string]interface{} `json:"metadatas"`
-	Distances  [][]float32                `json:"distances"`
-	Include    []string                   `json:"include"`
+// withTenantFilter 在 where 之上叠加 tenant_id 过滤条件（tenantID 为空时原样返回 where），
+// 用于在不改动 vector.Store 接口的前提下实现多用户业务隔离。
+func withTenantFilter(where map[string]interface{}, tenantID string) map[string]interface{} {
+	if tenantID == "" {
+		return where
+	}
+	filter := map[string]interface{}{"tenant_id": map[string]interface{}{"$eq": tenantID}}
+	if len(where) == 0 {
+		return filter
+	}
+	return map[string]interface{}{"$and": []map[string]interface{}{where, filter}}
 }
 
-//	type EmbeddingDoc struct {
-//	    ids       string                 `bson:"ids"`
-//	    metadatas map[string]interface{} `bson:"metadatas"`
-//	}
-//
-// 向量相似性检索
-func vectorSearch(queryVector []float32) ([]map[string]interface{}, error) {
-	// url := fmt.Sprintf("%s/tenants/%s/databases/%s/collections/%s/add", config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase, CollectionId)
-	// 检索 /api/v2/tenants/{tenant}/databases/{database}/collections/{collection_id}/query post
-	url := fmt.Sprintf("%s/tenants/%s/databases/%s/collections/%s/query", config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase, CollectionId)
-	Logger.Info("向量检索: " + url)
-	payload := struct {
-		QueryEmbeddings [][]float32 `json:"query_embeddings"`
-		NResults        int         `json:"n_results"`
-	}{
-		QueryEmbeddings: [][]float32{queryVector},
-		NResults:        5,
+// vectorSearchTool 是 outbox.VectorSearchFunc 的实现，把检索结果拼成一段文本喂回模型，
+// 供 outbox 包里的 vector_search 工具调用。
+func vectorSearchTool(ctx context.Context, query string, k int) (string, error) {
+	queryVector, err := generateEmbedding(ctx, query)
+	if err != nil {
+		return "", err
 	}
-	body, err := json.Marshal(payload)
+	results, err := vectorSearch(queryVector, k, nil)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	var b strings.Builder
+	for _, result := range results {
+		metadata, _ := result["metadata"].(map[string]interface{})
+		content, _ := metadata["content"].(string)
+		b.WriteString(content)
+		b.WriteString("\n---\n")
 	}
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	return b.String(), nil
+}
+
+// mmrSearch 先用 vectorSearch 召回 candidateN 条候选（连同各自的 embedding），
+// 再用 MMR 从中挑出 k 条兼顾相关性与多样性的结果。
+func mmrSearch(queryVector []float32, k int, lambda float32, tenantID string) ([]map[string]interface{}, error) {
+	const candidateN = 25
+
+	candidates, err := vectorSearch(queryVector, candidateN, withTenantFilter(nil, tenantID))
 	if err != nil {
-		fmt.Println("Error:", err)
 		return nil, err
 	}
-	// 打印 resp.body 并格式化
-	defer resp.Body.Close()
-	// bodyBytes, err := io.ReadAll(resp.Body)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// fmt.Println("Response Body:", string(bodyBytes))
-	var response VectorSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, err
+
+	metaByID := make(map[string]map[string]interface{}, len(candidates))
+	mmrCandidates := make([]vector.MMRCandidate, len(candidates))
+	for i, c := range candidates {
+		id := c["id"].(string)
+		metaByID[id] = c["metadata"].(map[string]interface{})
+		mmrCandidates[i] = vector.MMRCandidate{ID: id, Embedding: c["embedding"].([]float32)}
 	}
 
-	// 如果需要转换为 EmbeddingDoc 切片，可以手动映射
-	// 保存最终结果，每个查询对应一组匹配项
-	var allResults []map[string]interface{}
-	Logger.Info("向量检索结果: " + fmt.Sprintf("%v", response.IDs))
-	// 外层遍历：每个查询向量（通常是1个）
-	for i := range response.IDs {
-		// 内层遍历：每个匹配项
-		for j := range response.IDs[i] {
-			resultItem := map[string]interface{}{
-				"id":       response.IDs[i][j],
-				"metadata": response.Metadatas[i][j],
-				// 可选：添加距离信息
-				// "distance": response.Distances[i][j],
-			}
-			allResults = append(allResults, resultItem)
-		}
+	selectedIDs := vector.MMR(vector.Normalize(queryVector), mmrCandidates, k, lambda)
+
+	selected := make([]map[string]interface{}, len(selectedIDs))
+	for i, id := range selectedIDs {
+		selected[i] = map[string]interface{}{"id": id, "metadata": metaByID[id]}
 	}
-	return allResults, nil
+	return selected, nil
 }
 
-type VectorGetRessponse struct {
-	IDs        []string    `json:"ids"`
-	Embeddings [][]float32 `json:"embeddings"`
-}
+// hybridSearch 对向量检索和 BM25 检索的结果用 RRF（k=60）做融合排序。
+func hybridSearch(queryVector []float32, keywords string, where map[string]interface{}, tenantID string) ([]map[string]interface{}, error) {
+	const candidateN = 25
+	const rrfK = 60
 
-func vectorCompare(texts []WeightedText, id string) (bool, error) {
-	// fmt.Println("vectorCompare...", text, id)
-	embeddings, err := generateWeightedEmbedding(texts)
+	vectorResults, err := vectorSearch(queryVector, candidateN, withTenantFilter(where, tenantID))
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	// 通过 id 查询向量 /api/v2/tenants/{tenant}/databases/{database}/collections/{collection_id}/get post
-	url := fmt.Sprintf("%s/tenants/%s/databases/%s/collections/%s/get", config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase, CollectionId)
-	payload := struct {
-		IDs     []string `json:"ids"`
-		Include []string `json:"include"`
-	}{
-		IDs:     []string{id},
-		Include: []string{"embeddings"},
+
+	vectorIDs := make([]string, len(vectorResults))
+	metaByID := make(map[string]map[string]interface{}, len(vectorResults))
+	for i, r := range vectorResults {
+		id := r["id"].(string)
+		vectorIDs[i] = id
+		metaByID[id] = r["metadata"].(map[string]interface{})
+	}
+
+	var bm25IDs []string
+	if keywords != "" {
+		for _, r := range bmIndex.Search(keywords, candidateN) {
+			bm25IDs = append(bm25IDs, r.ID)
+		}
 	}
-	body, err := json.Marshal(payload)
+
+	fusedIDs := vector.ReciprocalRankFusion(rrfK, vectorIDs, bm25IDs)
+
+	fused := make([]map[string]interface{}, 0, len(fusedIDs))
+	for _, id := range fusedIDs {
+		metadata, ok := metaByID[id]
+		if !ok {
+			// 只命中 BM25 一路的文档，bmIndex 不知道 tenant_id，需要单独取回 metadata 再校验。
+			get, err := Store.Get([]string{id}, []string{"metadatas"})
+			if err != nil || len(get.Metadatas) == 0 {
+				continue
+			}
+			metadata = get.Metadatas[0]
+			if tenantID != "" && fmt.Sprintf("%v", metadata["tenant_id"]) != tenantID {
+				continue
+			}
+		}
+		fused = append(fused, map[string]interface{}{"id": id, "metadata": metadata})
+	}
+	return fused, nil
+}
+
+func vectorCompare(ctx context.Context, texts []WeightedText, id string) (bool, error) {
+	embeddings, err := generateWeightedEmbedding(ctx, texts)
 	if err != nil {
 		return false, err
 	}
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	result, err := Store.Get([]string{id}, []string{"embeddings"})
 	if err != nil {
 		return false, err
 	}
-	// fmt.Println(resp)
-	defer resp.Body.Close()
-	var response VectorGetRessponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return false, err
+	if len(result.Embeddings) == 0 {
+		return false, fmt.Errorf("record not found: %s", id)
 	}
-	// fmt.Println("response:", response.Embeddings[0])
-	compareResults := vector.BatchCompare(embeddings, response.Embeddings[0], 0.99)
-	// 将 compareResults 打印为字符串
-	// fmt.Println("compareResults:", compareResults)
-
-	return compareResults, nil
+	return vector.BatchCompare(embeddings, result.Embeddings[0], 0.99), nil
 }
 
 type DocumentResponse struct {
@@ -391,34 +650,26 @@ type DocumentResponse struct {
 	Metadatas []map[string]interface{} `json:"metadatas"`
 }
 
-func listDocuments(limit int, offset int) (DocumentResponse, error) {
-	// 获取列表 /api/v2/tenants/{tenant}/databases/{database}/collections/{collection_id}/get post
-	url := fmt.Sprintf("%s/tenants/%s/databases/%s/collections/%s/get", config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase, CollectionId)
-	// 请求体 limit offset include
-	payload := struct {
-		Limit   int      `json:"limit"`
-		Offset  int      `json:"offset"`
-		Include []string `json:"include"`
-	}{
-		Limit:   limit,
-		Offset:  offset,
-		Include: []string{"metadatas"},
-	}
-
-	body, err := json.Marshal(payload)
+// listDocuments 分页列出记录，tenantID 非空时只保留该租户的记录。List 本身不支持过滤，
+// 所以这里是列出后再按 metadata 过滤，分页是相对 Store.List 的原始结果而言的。
+func listDocuments(limit int, offset int, tenantID string) (DocumentResponse, error) {
+	result, err := Store.List(limit, offset)
 	if err != nil {
 		return DocumentResponse{}, err
 	}
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
-	if err != nil {
-		return DocumentResponse{}, err
+	if tenantID == "" {
+		return DocumentResponse{IDs: result.IDs, Metadatas: result.Metadatas}, nil
 	}
-	defer resp.Body.Close()
-	var response DocumentResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return DocumentResponse{}, err
+	ids := make([]string, 0, len(result.IDs))
+	metadatas := make([]map[string]interface{}, 0, len(result.Metadatas))
+	for i, metadata := range result.Metadatas {
+		if fmt.Sprintf("%v", metadata["tenant_id"]) != tenantID {
+			continue
+		}
+		ids = append(ids, result.IDs[i])
+		metadatas = append(metadatas, metadata)
 	}
-	return response, nil
+	return DocumentResponse{IDs: ids, Metadatas: metadatas}, nil
 }
 
 func enableCORS(handler http.Handler) http.Handler {
@@ -442,6 +693,11 @@ func enableCORS(handler http.Handler) http.Handler {
 
 // HTTP接口
 func main() {
+	flag.Parse()
+	if runCLIExportOrImport() {
+		return
+	}
+
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/generate", func(w http.ResponseWriter, r *http.Request) {
@@ -466,7 +722,8 @@ func main() {
 			weightedTexts[i] = WeightedText{Text: text.Text, Weight: text.Weight}
 		}
 		Logger.Info("正在存储: " + weightedTexts[0].Text)
-		id, err := generateAndStore(weightedTexts)
+		tenantID, _ := auth.TenantFromContext(r.Context())
+		id, err := generateAndStore(r.Context(), weightedTexts, tenantID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -487,6 +744,11 @@ func main() {
 				Text   string  `json:"text"`
 				Weight float32 `json:"weight"`
 			} `json:"texts"`
+			Where    map[string]interface{} `json:"where"`
+			Keywords string                 `json:"keywords"`
+			Rerank   string                 `json:"rerank"`
+			Lambda   float32                `json:"lambda"`
+			K        int                    `json:"k"`
 		}
 		if err := json.Unmarshal(body, &requestData); err != nil {
 			http.Error(w, "解析请求体失败", http.StatusBadRequest)
@@ -503,16 +765,30 @@ func main() {
 		}
 		// 生成文本向量
 		Logger.Info("生成文本向量: " + weightedTexts[0].Text)
-		vector, err := generateWeightedEmbedding(weightedTexts)
+		queryVector, err := generateWeightedEmbedding(r.Context(), weightedTexts)
 		if err != nil {
 			Logger.Error("生成向量失败: " + err.Error())
 			http.Error(w, "生成向量失败: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 		Logger.Info("生成向量成功: " + weightedTexts[0].Text)
-		// 执行向量检索
-		results, err := vectorSearch(vector)
-		// fmt.Printf("results: %v\n", results)
+
+		tenantID, _ := auth.TenantFromContext(r.Context())
+		var results []map[string]interface{}
+		if requestData.Rerank == "mmr" {
+			k := requestData.K
+			if k <= 0 {
+				k = 5
+			}
+			lambda := requestData.Lambda
+			if lambda == 0 {
+				lambda = 0.5
+			}
+			results, err = mmrSearch(queryVector, k, lambda, tenantID)
+		} else {
+			// 未要求 MMR 重排序时维持原有检索方式，如果带了 keywords 则与 BM25 结果做 RRF 融合
+			results, err = hybridSearch(queryVector, requestData.Keywords, requestData.Where, tenantID)
+		}
 		if err != nil {
 			http.Error(w, "检索失败: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -533,6 +809,15 @@ func main() {
 	})
 
 	mux.HandleFunc("/chat", Chat)
+	mux.HandleFunc("/chat/stream", ChatStream)
+
+	// /logs/tail 以 SSE 实时下发最近的结构化日志，供排查问题时直接在浏览器里看。
+	mux.HandleFunc("/logs/tail", LogTail.TailHandler)
+
+	// 对话记忆：列出/搜索/删除某个 session 下持久化的历史轮次
+	mux.HandleFunc("/memory/list", handleMemoryList)
+	mux.HandleFunc("/memory/search", handleMemorySearch)
+	mux.HandleFunc("/memory/delete", handleMemoryDelete)
 
 	mux.HandleFunc("/compare", func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
@@ -556,7 +841,7 @@ func main() {
 		for i, text := range requestData.Texts {
 			weightedTexts[i] = WeightedText{Text: text.Text, Weight: text.Weight}
 		}
-		result, err := vectorCompare(weightedTexts, requestData.ID)
+		result, err := vectorCompare(r.Context(), weightedTexts, requestData.ID)
 		Logger.Info("比较结果: " + fmt.Sprintf("%v", result))
 		if err != nil {
 			http.Error(w, "比较失败: "+err.Error(), http.StatusInternalServerError)
@@ -582,7 +867,8 @@ func main() {
 			return
 		}
 
-		documents, err := listDocuments(requestData.Limit, requestData.Offset)
+		tenantID, _ := auth.TenantFromContext(r.Context())
+		documents, err := listDocuments(requestData.Limit, requestData.Offset, tenantID)
 		if err != nil {
 			fmt.Println("获取文档失败:", err)
 			http.Error(w, "获取文档失败", http.StatusInternalServerError)
@@ -617,12 +903,70 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]string{"id": recordID})
 	})
 
+	// 注册新用户，tenantID 决定该用户之后只能看到自己写入的记录
+	mux.HandleFunc("/auth/register", func(w http.ResponseWriter, r *http.Request) {
+		var requestData struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			TenantID string `json:"tenant_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			http.Error(w, "解析请求体失败", http.StatusBadRequest)
+			return
+		}
+		if requestData.Username == "" || requestData.Password == "" || requestData.TenantID == "" {
+			http.Error(w, "username/password/tenant_id 不能为空", http.StatusBadRequest)
+			return
+		}
+		if err := Users.Register(requestData.Username, requestData.Password, requestData.TenantID); err != nil {
+			http.Error(w, "注册失败: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"username": requestData.Username})
+	})
+
+	// 登录换取 JWT，后续请求带 Authorization: Bearer <token>
+	mux.HandleFunc("/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var requestData struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			http.Error(w, "解析请求体失败", http.StatusBadRequest)
+			return
+		}
+		tenantID, err := Users.Authenticate(requestData.Username, requestData.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		token, err := auth.GenerateToken(config.JWTSecret, requestData.Username, tenantID, 24*time.Hour)
+		if err != nil {
+			http.Error(w, "签发 token 失败: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+	})
+
 	// 创建一个 task 接口, post，使用 NewTask 方法
 	mux.HandleFunc("/task", outbox.NewTask)
-	// 用户回答 PlanningTask answer
+	// /task 的 SSE 流式版本，支持并发任务和 tool_call 暂停/恢复
+	mux.HandleFunc("/task/stream", outbox.NewStreamTask)
+	// 用户回答 PlanningTask answer（老 /task 和 /task/stream 共用，按请求体里的 taskID 找到任务）
 	mux.HandleFunc("/planning/task/answer", outbox.PlanningTaskAnswer)
-	// 用户中断 PlanningTask
+	// 用户中断 PlanningTask（老 /task 和 /task/stream 共用，按请求体里的 taskID 找到任务）
 	mux.HandleFunc("/planning/task/interrupt", outbox.InterruptTask)
+	// 按 sessionID 实时推送 ask/answer 历史，前端可以用它渲染正在进行的任务而不用轮询
+	mux.HandleFunc("/session/stream", outbox.SessionStreamHandler)
+
+	// ActivityPub actor profile/inbox/outbox，把一个 wenko session 变成可被联邦订阅的 feed
+	mux.HandleFunc("/actors/", outbox.ActorsHandler)
+
+	// 可靠投递 outbox 的死信查看/重试，供运维排查 webhook/NATS/Kafka 投递失败的事件
+	mux.HandleFunc("/admin/outbox/dead", outbox.AdminDeadLetterHandler)
+	mux.HandleFunc("/admin/outbox/retry", outbox.AdminRetryDeadLetterHandler)
 
 	// 导出所有文本数据
 	mux.HandleFunc("/export", func(w http.ResponseWriter, r *http.Request) {
@@ -632,7 +976,7 @@ func main() {
 		}
 
 		fmt.Println("Received request to export all data.")
-		err := exportAllData()
+		path, count, err := runExport(export.FormatJSONL, "recursive", "")
 		if err != nil {
 			fmt.Printf("Error during data export: %v\n", err)
 			http.Error(w, fmt.Sprintf("Failed to export data: %v", err), http.StatusInternalServerError)
@@ -640,7 +984,10 @@ func main() {
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"message": "Data exported successfully to export_YYYYMMDD.md"})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": fmt.Sprintf("Data exported successfully to %s", path),
+			"records": count,
+		})
 	})
 
 	// mux.HandleFunc("/import", func(w http.ResponseWriter, r *http.Request) {
@@ -680,7 +1027,20 @@ func main() {
 	// 	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("数据已成功从 %s 导入并添加标记。", requestData.Filename)})
 	// })
 
-	handlerWithCORS := enableCORS(mux)
+	// /auth/ 下的接口本身就是在发 token，不能要求先带 token 才能访问；/actors/ 下的接口是
+	// ActivityPub 联邦端点，远程 Mastodon/Pleroma 实例不会有 wenko 签发的 JWT，这两类接口都
+	// 要绕开 wenko 自己的登录态，其余接口都要求登录。/actors/ 的收件箱已经由 httpsig 校验
+	// HTTP Signature 做了它自己的认证，绕开 mw.Auth 不会放开校验。
+	protected := mw.Auth(config.JWTSecret)(mux)
+	handlerWithAuth := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/auth/") || strings.HasPrefix(r.URL.Path, "/actors/") {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		protected.ServeHTTP(w, r)
+	})
+
+	handlerWithCORS := enableCORS(handlerWithAuth)
 
 	// 启动服务
 	fmt.Println("✅ 启动服务成功 -- Server running on :8080")
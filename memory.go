@@ -0,0 +1,187 @@
+package main
+
+import (
+	"books-vector-api/memory"
+	"books-vector-api/outbox"
+	"books-vector-api/vector"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MemStore 是 Chat/ChatStream/outbox.NewTask 共用的对话记忆存储。
+var MemStore memory.Store
+
+// newMemoryStore 根据 config.MemoryBackend 选择 memory.Store 实现："memory"
+// 用于本地开发（不持久化），默认用 MemoryCollectionId 指向的 Chroma collection。
+func newMemoryStore() memory.Store {
+	if config.MemoryBackend == "memory" {
+		fmt.Println("🌍记忆存储后端: memory")
+		return memory.NewInMemoryStore()
+	}
+	fmt.Println("🌍记忆存储后端: chroma")
+	return memory.NewChromaMemoryStore(vector.NewChromaStore(config.ChromaDBURL, config.ChromDBTenants, config.ChromaDBDatabase, MemoryCollectionId))
+}
+
+// newSessionStore 根据 config.SessionBackend 选择 outbox.Session 的持久化实现："memory"
+// 用于本地开发（不持久化），默认落 SessionDBPath 指向的 SQLite 文件。
+func newSessionStore() (outbox.SessionStore, error) {
+	if config.SessionBackend == "memory" {
+		fmt.Println("💾会话存储后端: memory")
+		return outbox.NewInMemorySessionStore(), nil
+	}
+	path := config.SessionDBPath
+	if path == "" {
+		path = "./sessions.db"
+	}
+	fmt.Println("💾会话存储后端: sqlite", path)
+	return outbox.NewSQLiteSessionStore(path)
+}
+
+// memoryTopK/memorySimilarityThreshold 返回配置值，缺省时给出合理默认，
+// 这样老的 config.json（没有这几个字段）也能正常跑起来。
+func memoryTopK() int {
+	if config.MemoryTopK > 0 {
+		return config.MemoryTopK
+	}
+	return 5
+}
+
+func memorySimilarityThreshold() float32 {
+	if config.MemorySimilarityThreshold > 0 {
+		return config.MemorySimilarityThreshold
+	}
+	return 0.5
+}
+
+func memoryTokenBudget() int {
+	if config.MemoryTokenBudget > 0 {
+		return config.MemoryTokenBudget
+	}
+	return 2000
+}
+
+// rememberTurn 生成 content 的向量并作为一条新的 Turn 写入 MemStore。
+func rememberTurn(ctx context.Context, sessionID, userID, role, content string) error {
+	if MemStore == nil || sessionID == "" || content == "" {
+		return nil
+	}
+	embedding, err := generateEmbedding(ctx, content)
+	if err != nil {
+		return err
+	}
+	turn := memory.Turn{
+		ID:        outbox.GenerateUUID(),
+		SessionID: sessionID,
+		UserID:    userID,
+		Role:      role,
+		Content:   content,
+		Timestamp: time.Now(),
+	}
+	return MemStore.Append(turn, embedding)
+}
+
+// recallContext 召回 sessionID 下和 query 语义相关的历史轮次，拼成一段可以直接
+// prepend 到 system 上下文里的文本，并按 MemoryTokenBudget（近似按字符数）截断。
+func recallContext(ctx context.Context, sessionID, query string) (string, error) {
+	if MemStore == nil || sessionID == "" {
+		return "", nil
+	}
+	queryEmbedding, err := generateEmbedding(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	turns, err := MemStore.Search(sessionID, queryEmbedding, memoryTopK(), memorySimilarityThreshold())
+	if err != nil {
+		return "", err
+	}
+	if len(turns) == 0 {
+		return "", nil
+	}
+
+	budget := memoryTokenBudget()
+	var b strings.Builder
+	b.WriteString("以下是本次会话中相关的历史对话，仅供参考：\n")
+	used := 0
+	for _, turn := range turns {
+		line := fmt.Sprintf("[%s] %s\n", turn.Role, turn.Content)
+		if used+len(line) > budget {
+			break
+		}
+		b.WriteString(line)
+		used += len(line)
+	}
+	return b.String(), nil
+}
+
+// handleMemoryList 列出一个 session 下的全部记忆，供调试和前端展示历史用。
+func handleMemoryList(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		SessionID string `json:"sessionId"`
+		Limit     int    `json:"limit"`
+		Offset    int    `json:"offset"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "解析请求体失败", http.StatusBadRequest)
+		return
+	}
+	turns, err := MemStore.List(requestData.SessionID, requestData.Limit, requestData.Offset)
+	if err != nil {
+		http.Error(w, "查询记忆失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(turns)
+}
+
+// handleMemorySearch 按语义相关性搜索一个 session 下的记忆。
+func handleMemorySearch(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		SessionID string  `json:"sessionId"`
+		Query     string  `json:"query"`
+		K         int     `json:"k"`
+		Threshold float32 `json:"threshold"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "解析请求体失败", http.StatusBadRequest)
+		return
+	}
+	k := requestData.K
+	if k <= 0 {
+		k = memoryTopK()
+	}
+	threshold := requestData.Threshold
+	if threshold <= 0 {
+		threshold = memorySimilarityThreshold()
+	}
+	queryEmbedding, err := generateEmbedding(r.Context(), requestData.Query)
+	if err != nil {
+		http.Error(w, "生成向量失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	turns, err := MemStore.Search(requestData.SessionID, queryEmbedding, k, threshold)
+	if err != nil {
+		http.Error(w, "查询记忆失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(turns)
+}
+
+// handleMemoryDelete 删除一条记忆。
+func handleMemoryDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id 不能为空", http.StatusBadRequest)
+		return
+	}
+	if err := MemStore.Delete(id); err != nil {
+		http.Error(w, "删除记忆失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"id": id})
+}
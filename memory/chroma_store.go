@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"books-vector-api/vector"
+)
+
+// ChromaMemoryStore 把 Turn 存成 vector.Store 记录：session_id/user_id/role/content/
+// timestamp 写进 metadata，Search 时先用 session_id 做 $eq 过滤再按向量相似度召回，
+// 复用 chunk0-6 里给多租户过滤建立的 where 子句机制。backend 通常是指向一个独立
+// memory collection 的 vector.Store，这样不会和 /search、/documents 混在一起。
+type ChromaMemoryStore struct {
+	backend vector.Store
+}
+
+// NewChromaMemoryStore 用任意 vector.Store 实现包装出一个 memory.Store。
+func NewChromaMemoryStore(backend vector.Store) *ChromaMemoryStore {
+	return &ChromaMemoryStore{backend: backend}
+}
+
+func (s *ChromaMemoryStore) Append(turn Turn, embedding []float32) error {
+	metadata := map[string]string{
+		"session_id": turn.SessionID,
+		"user_id":    turn.UserID,
+		"role":       turn.Role,
+		"content":    turn.Content,
+		"timestamp":  turn.Timestamp.Format(time.RFC3339),
+	}
+	return s.backend.Upsert(turn.ID, embedding, metadata)
+}
+
+func (s *ChromaMemoryStore) Search(sessionID string, queryEmbedding []float32, k int, maxDistance float32) ([]Turn, error) {
+	filter := map[string]interface{}{"session_id": map[string]interface{}{"$eq": sessionID}}
+	results, err := s.backend.Query(queryEmbedding, k, filter)
+	if err != nil {
+		return nil, err
+	}
+	turns := make([]Turn, 0, len(results))
+	for _, r := range results {
+		if r.Distance > maxDistance {
+			continue
+		}
+		turns = append(turns, turnFromMetadata(r.ID, r.Metadata))
+	}
+	return turns, nil
+}
+
+// List 把 limit/offset 直接交给 backend.List，分页作用在整个 backend 的原始记录上，之后
+// 才按 session_id 过滤，所以一页里属于该 session 的条数可能少于 limit；返回顺序就是
+// backend.List 的顺序，不按 timestamp 排序。
+func (s *ChromaMemoryStore) List(sessionID string, limit int, offset int) ([]Turn, error) {
+	result, err := s.backend.List(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	turns := make([]Turn, 0, len(result.IDs))
+	for i, id := range result.IDs {
+		metadata := result.Metadatas[i]
+		if fmt.Sprintf("%v", metadata["session_id"]) != sessionID {
+			continue
+		}
+		turns = append(turns, turnFromMetadata(id, metadata))
+	}
+	return turns, nil
+}
+
+func (s *ChromaMemoryStore) Delete(id string) error {
+	return s.backend.Delete([]string{id})
+}
+
+func turnFromMetadata(id string, metadata map[string]interface{}) Turn {
+	turn := Turn{ID: id}
+	if v, ok := metadata["session_id"].(string); ok {
+		turn.SessionID = v
+	}
+	if v, ok := metadata["user_id"].(string); ok {
+		turn.UserID = v
+	}
+	if v, ok := metadata["role"].(string); ok {
+		turn.Role = v
+	}
+	if v, ok := metadata["content"].(string); ok {
+		turn.Content = v
+	}
+	if v, ok := metadata["timestamp"].(string); ok {
+		if ts, err := time.Parse(time.RFC3339, v); err == nil {
+			turn.Timestamp = ts
+		}
+	}
+	return turn
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// InMemoryStore 是进程内的 Store 实现，不依赖外部 ChromaDB，适合本地开发或测试。
+type InMemoryStore struct {
+	mu    sync.RWMutex
+	turns map[string]memoryRecord
+}
+
+type memoryRecord struct {
+	turn      Turn
+	embedding []float32
+}
+
+// NewInMemoryStore 创建一个空的 InMemoryStore。
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{turns: make(map[string]memoryRecord)}
+}
+
+func (s *InMemoryStore) Append(turn Turn, embedding []float32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns[turn.ID] = memoryRecord{turn: turn, embedding: embedding}
+	return nil
+}
+
+func (s *InMemoryStore) Search(sessionID string, queryEmbedding []float32, k int, maxDistance float32) ([]Turn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type scored struct {
+		turn     Turn
+		distance float32
+	}
+	var candidates []scored
+	for _, rec := range s.turns {
+		if rec.turn.SessionID != sessionID {
+			continue
+		}
+		distance := cosineDistance(queryEmbedding, rec.embedding)
+		if distance > maxDistance {
+			continue
+		}
+		candidates = append(candidates, scored{turn: rec.turn, distance: distance})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	if k > 0 && len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	turns := make([]Turn, len(candidates))
+	for i, c := range candidates {
+		turns[i] = c.turn
+	}
+	return turns, nil
+}
+
+func (s *InMemoryStore) List(sessionID string, limit int, offset int) ([]Turn, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var all []Turn
+	for _, rec := range s.turns {
+		if rec.turn.SessionID == sessionID {
+			all = append(all, rec.turn)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Timestamp.Before(all[j].Timestamp) })
+
+	if offset >= len(all) {
+		return []Turn{}, nil
+	}
+	end := len(all)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return all[offset:end], nil
+}
+
+func (s *InMemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.turns, id)
+	return nil
+}
+
+// cosineDistance 返回 1 - cosine similarity，数值越小越相似，和 vector 包里
+// Chroma/Qdrant 的 distance 语义保持一致。
+func cosineDistance(a, b []float32) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 1
+	}
+	var dot, normA, normB float32
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	similarity := dot / float32(math.Sqrt(float64(normA))*math.Sqrt(float64(normB)))
+	return 1 - similarity
+}
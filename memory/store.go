@@ -0,0 +1,28 @@
+package memory
+
+import "time"
+
+// Turn 是一条对话轮次（用户/助手/工具），写入时会生成向量，之后按语义相关性召回，
+// 拼进后续对话的上下文里，让 Chat/NewTask 具备跨重启的长期记忆。
+type Turn struct {
+	ID        string
+	SessionID string
+	UserID    string
+	Role      string // "user" | "assistant" | "tool"
+	Content   string
+	Timestamp time.Time
+}
+
+// Store 抽象了对话记忆的持久化和检索，方便在内存实现（本地开发）和 Chroma 实现
+// （生产，跨进程重启持久化）之间切换，与 vector.Store 的分层方式保持一致。
+type Store interface {
+	// Append 写入一条已经算好向量的 Turn。
+	Append(turn Turn, embedding []float32) error
+	// Search 在指定 session 内按向量相似度召回最多 k 条记忆，maxDistance 对应
+	// vector.QueryResult.Distance 的上限（越小越相似），超过阈值的结果会被丢弃。
+	Search(sessionID string, queryEmbedding []float32, k int, maxDistance float32) ([]Turn, error)
+	// List 按时间顺序分页列出一个 session 下的全部记忆。
+	List(sessionID string, limit int, offset int) ([]Turn, error)
+	// Delete 删除一条记忆。
+	Delete(id string) error
+}
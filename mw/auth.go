@@ -0,0 +1,36 @@
+package mw
+
+import (
+	"books-vector-api/auth"
+	"net/http"
+)
+
+// Auth 返回一个校验 Authorization: Bearer <jwt> 的中间件，校验通过后把 tenant_id/subject
+// 写入 request context，供 addToChromaDB/vectorSearch 等读取做多用户隔离。
+// OPTIONS 预检请求放行，交给外层 enableCORS 处理。
+func Auth(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			const prefix = "Bearer "
+			header := r.Header.Get("Authorization")
+			if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+				http.Error(w, "缺少或无效的 Authorization 头", http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := auth.ParseToken(secret, header[len(prefix):])
+			if err != nil {
+				http.Error(w, "无效的 token: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			r = r.WithContext(auth.WithTenant(r.Context(), claims))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
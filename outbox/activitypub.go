@@ -0,0 +1,382 @@
+package outbox
+
+import (
+	"books-vector-api/internal/linkedhashmap"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-fed/httpsig"
+)
+
+// ActorKeyDir 是 actor RSA keypair 的持久化目录（每个 actor 一个 PEM 文件），由 main.go
+// 在 init() 里通过 InitActivityPub 注入，和 InitModelProvider/InitSessionStore 一样的接线方式。
+var ActorKeyDir = "./actors"
+
+// PublicBaseURL 非空时作为 actor IRI 的协议+host 前缀（例如 "https://wenko.example"）；
+// 留空则退回到收到请求的 r.Host，方便本地开发不用配置。
+var PublicBaseURL string
+
+// InitActivityPub 设置 actor keypair 的持久化目录。
+func InitActivityPub(keyDir string) {
+	if keyDir != "" {
+		ActorKeyDir = keyDir
+	}
+}
+
+// Actor 是一个可以被其他 ActivityPub 实例关注的行为体：一个 wenko sessionID 对应一个
+// Actor，持有一把用于给投递请求签名的 RSA keypair。
+type Actor struct {
+	Name       string
+	PrivateKey *rsa.PrivateKey
+}
+
+func actorKeyPath(name string) string {
+	return filepath.Join(ActorKeyDir, name+".pem")
+}
+
+// LoadOrCreateActor 读取 name 对应的 PEM 私钥，不存在就生成一把新的 2048 位 RSA keypair
+// 并持久化下来——同一个 name 跨重启要用同一把钥匙签名，否则联邦的另一端会校验不过。
+func LoadOrCreateActor(name string) (*Actor, error) {
+	path := actorKeyPath(name)
+	if data, err := os.ReadFile(path); err == nil {
+		key, err := parsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return &Actor{Name: name, PrivateKey: key}, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(ActorKeyDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, encodePrivateKeyPEM(key), 0600); err != nil {
+		return nil, err
+	}
+	return &Actor{Name: name, PrivateKey: key}, nil
+}
+
+func encodePrivateKeyPEM(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func parsePrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("无效的 PEM 私钥")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// PublicKeyPEM 返回可以放进 actor profile 的 publicKey.publicKeyPem 字段的 PEM 编码公钥。
+func (a *Actor) PublicKeyPEM() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(&a.PrivateKey.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func baseURL(r *http.Request) string {
+	if PublicBaseURL != "" {
+		return strings.TrimSuffix(PublicBaseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func actorIRI(base, name string) string {
+	return fmt.Sprintf("%s/actors/%s", base, name)
+}
+
+// ActorsHandler 是 /actors/ 前缀下所有路径的入口，按 {name} 之后的段落分发到
+// actor profile、inbox 接收、outbox 历史这三个 handler——这个仓库至今都是手写 mux 路由，
+// 没有依赖支持路径变量的 http.ServeMux，这里延续同样的写法。
+func ActorsHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/actors/"), "/")
+	segments := strings.Split(rest, "/")
+	name := segments[0]
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(segments) == 1:
+		actorProfileHandler(w, r, name)
+	case len(segments) == 2 && segments[1] == "inbox" && r.Method == http.MethodPost:
+		actorInboxHandler(w, r, name)
+	case len(segments) == 2 && segments[1] == "outbox" && r.Method == http.MethodGet:
+		actorOutboxHandler(w, r, name)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// actorProfileHandler 是 GET /actors/{name}，返回最小可用的 ActivityPub actor profile，
+// 联邦的另一端据此找到 inbox/outbox 地址和校验签名用的公钥。
+func actorProfileHandler(w http.ResponseWriter, r *http.Request, name string) {
+	actor, err := LoadOrCreateActor(name)
+	if err != nil {
+		http.Error(w, "加载 actor 失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pubKeyPEM, err := actor.PublicKeyPEM()
+	if err != nil {
+		http.Error(w, "导出公钥失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	iri := actorIRI(baseURL(r), name)
+	w.Header().Set("Content-Type", "application/activity+json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"@context":          "https://www.w3.org/ns/activitystreams",
+		"id":                iri,
+		"type":              "Person",
+		"preferredUsername": name,
+		"inbox":             iri + "/inbox",
+		"outbox":            iri + "/outbox",
+		"publicKey": map[string]interface{}{
+			"id":           iri + "#main-key",
+			"owner":        iri,
+			"publicKeyPem": pubKeyPEM,
+		},
+	})
+}
+
+// noteActivity 把一条 MessageType 映射成 ActivityPub 的 Create{Note} activity：Content 进
+// Note.content，activity 自己的 IRI 由 ActionID 得出（<actor>/activities/<ActionID>），Meta 里的
+// to/cc/attachment 原样透传。
+func noteActivity(base, name string, index int, entry MessageType) map[string]interface{} {
+	actor := actorIRI(base, name)
+	note := map[string]interface{}{
+		"id":           entry.ActionID,
+		"type":         "Note",
+		"attributedTo": actor,
+		"content":      entry.Payload.Content,
+	}
+	if to, ok := entry.Payload.Meta.Get("to"); ok {
+		note["to"] = to
+	}
+	if cc, ok := entry.Payload.Meta.Get("cc"); ok {
+		note["cc"] = cc
+	}
+	if attachment, ok := entry.Payload.Meta.Get("attachment"); ok {
+		note["attachment"] = attachment
+	}
+	return map[string]interface{}{
+		"@context": "https://www.w3.org/ns/activitystreams",
+		"id":       fmt.Sprintf("%s/activities/%s", actor, entry.ActionID),
+		"type":     "Create",
+		"actor":    actor,
+		"object":   note,
+	}
+}
+
+// actorOutboxHandler 是 GET /actors/{name}/outbox：把 Sessions 里 name 这个 sessionID 下的
+// 全部历史 entries 按发生顺序包成一个 OrderedCollection，这样 wenko 的一次会话就是一条
+// 任何 Mastodon/Pleroma 实例都能订阅的 feed。
+func actorOutboxHandler(w http.ResponseWriter, r *http.Request, name string) {
+	if Sessions == nil {
+		http.Error(w, "会话存储未初始化", http.StatusInternalServerError)
+		return
+	}
+	entries, _ := Sessions.GetEntries(name)
+	base := baseURL(r)
+	items := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		items[i] = noteActivity(base, name, i, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/activity+json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"@context":     "https://www.w3.org/ns/activitystreams",
+		"id":           actorIRI(base, name) + "/outbox",
+		"type":         "OrderedCollection",
+		"totalItems":   len(items),
+		"orderedItems": items,
+	})
+}
+
+// actorInboxHandler 是 POST /actors/{name}/inbox：校验发件 actor 的 HTTP Signature（公钥从
+// 对方的 actor profile 现取），通过后把收到的 activity 记进 name 这个 sessionID 的历史，
+// 这样联邦发来的 Follow/Create 之类的 activity 也能在 /session/stream 里被看到。
+func actorInboxHandler(w http.ResponseWriter, r *http.Request, name string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "读取请求体失败", http.StatusBadRequest)
+		return
+	}
+
+	var activity struct {
+		ID     string                 `json:"id"`
+		Type   string                 `json:"type"`
+		Actor  string                 `json:"actor"`
+		Object map[string]interface{} `json:"object"`
+	}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		http.Error(w, "解析 activity 失败", http.StatusBadRequest)
+		return
+	}
+
+	// 验证必须无条件执行：activity.Actor 来自未校验的请求体，省略它不能当成免验证的通道。
+	if activity.Actor == "" {
+		http.Error(w, "缺少 actor", http.StatusBadRequest)
+		return
+	}
+	remoteKey, err := fetchRemoteActorKey(r.Context(), activity.Actor)
+	if err != nil {
+		http.Error(w, "获取发送方公钥失败: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	verifier, err := httpsig.NewVerifier(r)
+	if err != nil {
+		http.Error(w, "缺少 Signature: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := verifier.Verify(remoteKey, httpsig.RSA_SHA256); err != nil {
+		http.Error(w, "签名校验失败: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	content, _ := activity.Object["content"].(string)
+	inboxMeta := linkedhashmap.New[string, interface{}]()
+	inboxMeta.Set("activity", activity.Type)
+	inboxMeta.Set("from", activity.Actor)
+	recordSessionEntry(name, MessageType{
+		Type: "inbox",
+		Payload: PayloadType{
+			Content: content,
+			Meta:    inboxMeta,
+		},
+		ActionID: activity.ID,
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validateActorIRI 拒绝 scheme 不是 https、host 缺失，或解析到私有/回环/链路本地地址的
+// actorIRI：这个 IRI 在签名校验通过之前就来自未认证的请求体，不挡住的话 inbox 就是一个
+// 能让服务器对任意内网 host:port 发 GET 的 SSRF 跳板。
+func validateActorIRI(actorIRI string) error {
+	parsed, err := url.Parse(actorIRI)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("actor IRI 必须是 https")
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("actor IRI 缺少 host")
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+			return fmt.Errorf("actor IRI 解析到不允许访问的地址")
+		}
+	}
+	return nil
+}
+
+// fetchRemoteActorKey 取回 actorIRI 的 actor profile，解析出 publicKey.publicKeyPem，
+// 用来校验一次 inbox 投递请求的 Signature。
+func fetchRemoteActorKey(ctx context.Context, actorIRI string) (*rsa.PublicKey, error) {
+	if err := validateActorIRI(actorIRI); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, actorIRI, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/activity+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var profile struct {
+		PublicKey struct {
+			PublicKeyPem string `json:"publicKeyPem"`
+		} `json:"publicKey"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(profile.PublicKey.PublicKeyPem))
+	if block == nil {
+		return nil, fmt.Errorf("远端 actor 没有可用的公钥")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("远端 actor 公钥不是 RSA")
+	}
+	return rsaPub, nil
+}
+
+// Deliver 以 actor 的身份把 body（要发出的 activity JSON）签名后 POST 给 inboxURL：
+// Signature 覆盖 (request-target)/host/date/digest，对方实例按 actor 公钥校验通过才会
+// 接受这条 activity，这就是 outbox 变成"可被联邦订阅"的关键一步。
+func Deliver(ctx context.Context, actor *Actor, keyID, inboxURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inboxURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/activity+json")
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+
+	signer, _, err := httpsig.NewSigner(
+		[]httpsig.Algorithm{httpsig.RSA_SHA256},
+		httpsig.DigestSha256,
+		[]string{httpsig.RequestTarget, "host", "date", "digest"},
+		httpsig.Signature,
+		0,
+	)
+	if err != nil {
+		return err
+	}
+	if err := signer.SignRequest(actor.PrivateKey, keyID, req, body); err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("投递失败: inbox 返回 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -1,8 +1,15 @@
 package outbox
 
+import (
+	"sync"
+
+	"books-vector-api/internal/linkedhashmap"
+	"books-vector-api/log"
+)
+
 type PayloadType struct {
-	Content string                 `json:"content"`
-	Meta    map[string]interface{} `json:"meta"`
+	Content string                                         `json:"content"`
+	Meta    *linkedhashmap.OrderedMap[string, interface{}] `json:"meta"`
 }
 
 type MessageType struct {
@@ -11,42 +18,176 @@ type MessageType struct {
 	ActionID string      `json:"actionID"`
 }
 
-// 会话
+// writeOp 是 Session 后台刷盘 goroutine 要对 store 重放的一次写操作。
+type writeOp func(store SessionStore) error
+
+// sessionWriteQueueSize 是异步刷盘队列的容量，打满时新的写操作直接丢弃并打 warn 日志：
+// 内存缓存已经是最新的，丢的只是持久化副本，热路径不会被慢的 SessionStore 拖慢。
+const sessionWriteQueueSize = 256
+
+// subscriberBufferSize 是每个订阅者 channel 的容量，写满后按丢最旧的策略腾地方：
+// 前端要看到的是“当前状态”而不是完整历史（GetEntries 已经负责补齐历史），
+// 丢最旧比丢最新更不容易让订阅者错过最后一条 token。
+const subscriberBufferSize = 32
+
+// Session 是 sessionID -> MessageType 列表的读写入口：GetEntries 按需从 store 懒加载并
+// 缓存在内存，Add/Update/Delete 系列方法先同步更新内存缓存，再把对应的持久化操作丢进
+// 一个有界 channel，由后台 goroutine 串行写回 store；同时把每次变更广播给该 sessionID
+// 上注册的订阅者，供 SessionStreamHandler 之类的 SSE 接口实时转发。
 type Session struct {
-	sessionIDMap map[string][]MessageType
+	store SessionStore
+
+	mu    sync.Mutex
+	cache map[string][]MessageType
+
+	writes chan writeOp
+
+	subMu       sync.RWMutex
+	subscribers map[string]map[chan MessageType]struct{}
 }
 
-func NewSession() *Session {
-	return &Session{
-		sessionIDMap: make(map[string][]MessageType),
+// NewSession 创建一个以 store 为持久化后端的 Session，并启动后台刷盘 goroutine。
+func NewSession(store SessionStore) *Session {
+	s := &Session{
+		store:       store,
+		cache:       make(map[string][]MessageType),
+		writes:      make(chan writeOp, sessionWriteQueueSize),
+		subscribers: make(map[string]map[chan MessageType]struct{}),
 	}
+	go s.flushLoop()
+	return s
 }
+
+func (s *Session) flushLoop() {
+	for op := range s.writes {
+		if err := op(s.store); err != nil {
+			logError("会话持久化失败", log.Err(err))
+		}
+	}
+}
+
+// enqueue 把一次写操作排进刷盘队列，队列满时直接丢弃而不是阻塞调用方。
+func (s *Session) enqueue(op writeOp) {
+	select {
+	case s.writes <- op:
+	default:
+		logWarn("会话刷盘队列已满，本次写入未持久化")
+	}
+}
+
+// load 返回 sessionID 对应的 entries，缓存未命中时从 store 懒加载一次，调用方需持锁。
+func (s *Session) load(sessionID string) []MessageType {
+	if entries, ok := s.cache[sessionID]; ok {
+		return entries
+	}
+	entries, err := s.store.GetEntries(sessionID)
+	if err != nil {
+		logError("加载会话失败", log.String("sessionID", sessionID), log.Err(err))
+	}
+	s.cache[sessionID] = entries
+	return entries
+}
+
 func (s *Session) AddEntry(sessionID string, entry MessageType) {
-	s.sessionIDMap[sessionID] = append(s.sessionIDMap[sessionID], entry)
+	s.mu.Lock()
+	s.cache[sessionID] = append(s.load(sessionID), entry)
+	s.enqueue(func(store SessionStore) error { return store.AddEntry(sessionID, entry) })
+	s.mu.Unlock()
+	s.publish(sessionID, entry)
+	if deliveryOutbox != nil {
+		deliveryOutbox.Enqueue(entry)
+	}
 }
 
 func (s *Session) GetEntries(sessionID string) ([]MessageType, bool) {
-	entries, exists := s.sessionIDMap[sessionID]
-	return entries, exists
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.load(sessionID)
+	return entries, len(entries) > 0
 }
 
 func (s *Session) DeleteEntries(sessionID string) {
-	delete(s.sessionIDMap, sessionID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, sessionID)
+	s.enqueue(func(store SessionStore) error { return store.DeleteEntries(sessionID) })
 }
 
 // UpdateEntry
 func (s *Session) UpdateEntry(sessionID string, index int, entry MessageType) bool {
-	if entries, exists := s.sessionIDMap[sessionID]; exists && index >= 0 && index < len(entries) {
-		entries[index] = entry
-		return true
+	s.mu.Lock()
+	entries := s.load(sessionID)
+	if index < 0 || index >= len(entries) {
+		s.mu.Unlock()
+		return false
 	}
-	return false
+	entries[index] = entry
+	s.enqueue(func(store SessionStore) error { return store.UpdateEntry(sessionID, index, entry) })
+	s.mu.Unlock()
+	s.publish(sessionID, entry)
+	return true
 }
 
 // 删除最后一条信息
 func (s *Session) DeleteLastEntry(sessionID string) {
-	entries, exists := s.sessionIDMap[sessionID]
-	if exists && len(entries) > 0 {
-		s.sessionIDMap[sessionID] = entries[:len(entries)-1]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.load(sessionID)
+	if len(entries) == 0 {
+		return
+	}
+	s.cache[sessionID] = entries[:len(entries)-1]
+	s.enqueue(func(store SessionStore) error { return store.DeleteLastEntry(sessionID) })
+}
+
+// Subscribe 注册一个 sessionID 的实时订阅者，返回接收后续 AddEntry/UpdateEntry 的只读
+// channel 和取消订阅函数；调用方（通常是 SSE handler）应当在连接断开时调用 unsubscribe，
+// 否则 channel 会一直留在 subscribers 里造成泄漏。
+func (s *Session) Subscribe(sessionID string) (<-chan MessageType, func()) {
+	ch := make(chan MessageType, subscriberBufferSize)
+
+	s.subMu.Lock()
+	if s.subscribers[sessionID] == nil {
+		s.subscribers[sessionID] = make(map[chan MessageType]struct{})
+	}
+	s.subscribers[sessionID][ch] = struct{}{}
+	s.subMu.Unlock()
+
+	unsubscribe := func() {
+		s.subMu.Lock()
+		delete(s.subscribers[sessionID], ch)
+		if len(s.subscribers[sessionID]) == 0 {
+			delete(s.subscribers, sessionID)
+		}
+		s.subMu.Unlock()
+		close(ch)
+	}
+	return ch, unsubscribe
+}
+
+// publish 把 entry 非阻塞地广播给 sessionID 当前的全部订阅者：跟不上的订阅者丢最旧的
+// 一条腾地方再塞入新条目，而不是直接丢掉刚产生的这条，避免订阅者停在过时的状态上。
+func (s *Session) publish(sessionID string, entry MessageType) {
+	s.subMu.RLock()
+	subs := s.subscribers[sessionID]
+	chans := make([]chan MessageType, 0, len(subs))
+	for ch := range subs {
+		chans = append(chans, ch)
+	}
+	s.subMu.RUnlock()
+
+	for _, ch := range chans {
+		select {
+		case ch <- entry:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- entry:
+			default:
+			}
+		}
 	}
 }
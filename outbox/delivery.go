@@ -0,0 +1,198 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"books-vector-api/log"
+)
+
+// deliveryOutbox 是进程内唯一的可靠投递 outbox，由 main.go 在 init() 里通过
+// InitDeliveryOutbox 注入，和 Sessions/taskEventStore 一样的接线方式。未注入时
+// Session.AddEntry 会跳过入队，不影响会话本身的读写。
+var deliveryOutbox *DeliveryOutbox
+
+// InitDeliveryOutbox 注入进程内唯一的 DeliveryOutbox。
+func InitDeliveryOutbox(o *DeliveryOutbox) {
+	deliveryOutbox = o
+}
+
+// DeliveryOutbox 把 Session.AddEntry 产生的 MessageType 按它的 Type 路由给注册的 Sink，
+// 持久化在 DeliveryStore 里保证进程重启不丢，真正的投递交给 DeliveryWorkerPool。
+type DeliveryOutbox struct {
+	store *DeliveryStore
+	route map[string]string // MessageType.Type -> sink 名字
+	sinks map[string]Sink   // sink 名字 -> Sink
+}
+
+// NewDeliveryOutbox 创建一个以 store 为持久化后端的空 DeliveryOutbox，还没有注册任何路由。
+func NewDeliveryOutbox(store *DeliveryStore) *DeliveryOutbox {
+	return &DeliveryOutbox{
+		store: store,
+		route: make(map[string]string),
+		sinks: make(map[string]Sink),
+	}
+}
+
+// RegisterSink 给 o 注册一个命名的 Sink，供 Route 引用。
+func (o *DeliveryOutbox) RegisterSink(name string, sink Sink) {
+	o.sinks[name] = sink
+}
+
+// Route 声明 msgType 这一类 MessageType（如 "ask"）要投递给 sinkName 对应的 Sink。
+func (o *DeliveryOutbox) Route(msgType, sinkName string) {
+	o.route[msgType] = sinkName
+}
+
+// Enqueue 把 entry 记成一次待投递事件：entry.Type 没有配置路由，或者没有 ActionID
+// （没法去重）的条目直接忽略。
+func (o *DeliveryOutbox) Enqueue(entry MessageType) {
+	sinkName, ok := o.route[entry.Type]
+	if !ok || entry.ActionID == "" {
+		return
+	}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		logError("序列化投递事件失败", log.Err(err))
+		return
+	}
+	if _, err := o.store.Enqueue(entry.ActionID, entry.Type, sinkName, string(payload)); err != nil {
+		logError("排队投递事件失败", log.String("actionID", entry.ActionID), log.Err(err))
+	}
+}
+
+// deliveryMinBackoff/deliveryMaxBackoff 是失败重试的退避区间：第一次失败 1 秒后重试，
+// 指数翻倍，封顶 5 分钟。
+const (
+	deliveryMinBackoff = time.Second
+	deliveryMaxBackoff = 5 * time.Minute
+)
+
+// deliveryBackoff 按第 attempts 次失败算退避时长：1s, 2s, 4s, ... 封顶 deliveryMaxBackoff。
+func deliveryBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := deliveryMinBackoff << uint(attempts-1)
+	if backoff <= 0 || backoff > deliveryMaxBackoff {
+		return deliveryMaxBackoff
+	}
+	return backoff
+}
+
+// DeliveryWorkerPool 按 pollInterval 轮询 DeliveryStore 里到期的事件，按 sink 分组
+// 限流并发投递，失败的按指数退避重排，达到 maxAttempts 次转入死信。
+type DeliveryWorkerPool struct {
+	outbox       *DeliveryOutbox
+	perSinkLimit int
+	maxAttempts  int
+	pollInterval time.Duration
+
+	sem map[string]chan struct{} // sink 名字 -> 并发限流信号量
+}
+
+// NewDeliveryWorkerPool 创建一个投递 outbox 里到期事件的 worker pool。
+func NewDeliveryWorkerPool(outbox *DeliveryOutbox, perSinkLimit, maxAttempts int, pollInterval time.Duration) *DeliveryWorkerPool {
+	sem := make(map[string]chan struct{}, len(outbox.sinks))
+	for name := range outbox.sinks {
+		sem[name] = make(chan struct{}, perSinkLimit)
+	}
+	return &DeliveryWorkerPool{
+		outbox:       outbox,
+		perSinkLimit: perSinkLimit,
+		maxAttempts:  maxAttempts,
+		pollInterval: pollInterval,
+		sem:          sem,
+	}
+}
+
+// Run 是一个阻塞循环，按 pollInterval 轮询待投递事件并发起投递，直到 ctx 取消。
+func (p *DeliveryWorkerPool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.dispatchDue(ctx)
+		}
+	}
+}
+
+func (p *DeliveryWorkerPool) dispatchDue(ctx context.Context) {
+	events, err := p.outbox.store.DueEvents()
+	if err != nil {
+		logError("查询待投递事件失败", log.Err(err))
+		return
+	}
+	for _, event := range events {
+		sink, ok := p.outbox.sinks[event.Sink]
+		if !ok {
+			continue
+		}
+		sem := p.sem[event.Sink]
+		select {
+		case sem <- struct{}{}:
+		default:
+			// 这个 sink 的并发已经跑满，留到下一轮 poll 再试，不阻塞其它 sink。
+			continue
+		}
+		go func(event DeliveryEvent, sink Sink) {
+			defer func() { <-sem }()
+			p.deliverOne(ctx, event, sink)
+		}(event, sink)
+	}
+}
+
+func (p *DeliveryWorkerPool) deliverOne(ctx context.Context, event DeliveryEvent, sink Sink) {
+	if err := sink.Deliver(ctx, event); err != nil {
+		logWarn("投递事件失败", log.Int("id", int(event.ID)), log.String("sink", event.Sink), log.Err(err))
+		if err := p.outbox.store.MarkFailed(event.ID, deliveryBackoff(event.Attempts+1), p.maxAttempts); err != nil {
+			logError("记录投递失败状态出错", log.Err(err))
+		}
+		return
+	}
+	if err := p.outbox.store.MarkDone(event.ID); err != nil {
+		logError("标记投递完成出错", log.Err(err))
+	}
+}
+
+// AdminDeadLetterHandler 是 GET /admin/outbox/dead：列出所有进入死信状态的投递事件，
+// 方便运维判断是哪个 sink、哪条 activity 出了问题。
+func AdminDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	if deliveryOutbox == nil {
+		http.Error(w, "投递 outbox 未初始化", http.StatusInternalServerError)
+		return
+	}
+	events, err := deliveryOutbox.store.DeadLetters()
+	if err != nil {
+		http.Error(w, "查询死信失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// AdminRetryDeadLetterHandler 是 POST /admin/outbox/retry?id=：把一条死信事件重新排回
+// pending，交给下一轮 DeliveryWorkerPool 轮询重试。
+func AdminRetryDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	if deliveryOutbox == nil {
+		http.Error(w, "投递 outbox 未初始化", http.StatusInternalServerError)
+		return
+	}
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "id 参数无效", http.StatusBadRequest)
+		return
+	}
+	if err := deliveryOutbox.store.Requeue(id); err != nil {
+		http.Error(w, "重试失败: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "requeued"})
+}
@@ -0,0 +1,98 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// Sink 是投递事件的目的地：WebhookSink/NATSSink/KafkaSink 各自实现 Deliver，
+// DeliveryWorkerPool 只认这个接口，换/加目的地不用改调度逻辑。
+type Sink interface {
+	Deliver(ctx context.Context, event DeliveryEvent) error
+}
+
+// WebhookSink 把事件的 payload_json 原样 POST 给 URL，并用 Secret 对 body 算一个
+// HMAC-SHA256 签名放进 X-Wenko-Signature 头，供对方验证请求确实来自本实例而不是伪造的。
+type WebhookSink struct {
+	URL    string
+	Secret string
+}
+
+func NewWebhookSink(url, secret string) *WebhookSink {
+	return &WebhookSink{URL: url, Secret: secret}
+}
+
+func (s *WebhookSink) Deliver(ctx context.Context, event DeliveryEvent) error {
+	body := []byte(event.PayloadJSON)
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Wenko-Signature", signature)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// NATSSink 把事件发布到一个 NATS subject。
+type NATSSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSSink 连接 url 并返回一个发布到 subject 的 NATSSink。
+func NewNATSSink(url, subject string) (*NATSSink, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSSink{conn: conn, subject: subject}, nil
+}
+
+func (s *NATSSink) Deliver(ctx context.Context, event DeliveryEvent) error {
+	return s.conn.Publish(s.subject, []byte(event.PayloadJSON))
+}
+
+// KafkaSink 把事件写到一个 Kafka topic，用 ActionID 当分区 key 保证同一条 activity
+// 的多次重试落在同一个分区、保持顺序。
+type KafkaSink struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaSink 创建一个写到 brokers/topic 的 KafkaSink。
+func NewKafkaSink(brokers []string, topic string) *KafkaSink {
+	return &KafkaSink{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Topic:    topic,
+			Balancer: &kafka.Hash{},
+		},
+	}
+}
+
+func (s *KafkaSink) Deliver(ctx context.Context, event DeliveryEvent) error {
+	return s.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.ActionID),
+		Value: []byte(event.PayloadJSON),
+	})
+}
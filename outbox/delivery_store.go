@@ -0,0 +1,142 @@
+package outbox
+
+import (
+	"database/sql"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DeliveryEvent 是 delivery_events 表的一行：Session.AddEntry 产生的一条 MessageType，
+// 连同它要投递到哪个 Sink，idempotency key 是 (ActionID, Sink) 这一对，同一条 entry
+// 重复入队到同一个 sink 只会被处理一次。
+type DeliveryEvent struct {
+	ID            int64
+	ActionID      string
+	MsgType       string
+	Sink          string
+	PayloadJSON   string
+	Status        string // "pending" | "done" | "dead"
+	Attempts      int
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// DeliveryStore 是投递事件的 SQLite 持久化实现。
+type DeliveryStore struct {
+	db *sql.DB
+}
+
+// NewDeliveryStore 打开（或创建）dbPath 指向的 SQLite 文件并确保 delivery_events 表存在。
+func NewDeliveryStore(dbPath string) (*DeliveryStore, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	schema := `CREATE TABLE IF NOT EXISTS delivery_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		action_id TEXT NOT NULL,
+		msg_type TEXT NOT NULL,
+		sink TEXT NOT NULL,
+		payload_json TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		UNIQUE (action_id, sink)
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		return nil, err
+	}
+	return &DeliveryStore{db: db}, nil
+}
+
+// Enqueue 写入一条待投递事件，(actionID, sink) 重复时直接返回已存在的那一行的 id。
+func (s *DeliveryStore) Enqueue(actionID, msgType, sink, payloadJSON string) (int64, error) {
+	now := time.Now()
+	result, err := s.db.Exec(
+		`INSERT OR IGNORE INTO delivery_events
+		 (action_id, msg_type, sink, payload_json, status, attempts, next_attempt_at, created_at)
+		 VALUES (?, ?, ?, ?, 'pending', 0, ?, ?)`,
+		actionID, msgType, sink, payloadJSON, now, now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		row := s.db.QueryRow("SELECT id FROM delivery_events WHERE action_id = ? AND sink = ?", actionID, sink)
+		if err := row.Scan(&id); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// DueEvents 取回所有到了重试时间、还没投递成功的事件。
+func (s *DeliveryStore) DueEvents() ([]DeliveryEvent, error) {
+	return s.query(`SELECT id, action_id, msg_type, sink, payload_json, status, attempts, next_attempt_at, created_at
+		FROM delivery_events WHERE status = 'pending' AND next_attempt_at <= ? ORDER BY created_at ASC`, time.Now())
+}
+
+// DeadLetters 取回所有重试次数耗尽、进入死信状态的事件，供管理接口查看。
+func (s *DeliveryStore) DeadLetters() ([]DeliveryEvent, error) {
+	return s.query(`SELECT id, action_id, msg_type, sink, payload_json, status, attempts, next_attempt_at, created_at
+		FROM delivery_events WHERE status = 'dead' ORDER BY created_at ASC`)
+}
+
+func (s *DeliveryStore) query(query string, args ...interface{}) ([]DeliveryEvent, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []DeliveryEvent
+	for rows.Next() {
+		var e DeliveryEvent
+		if err := rows.Scan(&e.ID, &e.ActionID, &e.MsgType, &e.Sink, &e.PayloadJSON, &e.Status, &e.Attempts, &e.NextAttemptAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// MarkDone 把事件标记为投递成功。
+func (s *DeliveryStore) MarkDone(id int64) error {
+	_, err := s.db.Exec("UPDATE delivery_events SET status = 'done' WHERE id = ?", id)
+	return err
+}
+
+// MarkFailed 记录一次投递失败：attempts 加一，按 backoff 推迟下一次重试时间，
+// 达到 maxAttempts 后转入死信（status = 'dead'），不再被 DueEvents 取到。
+func (s *DeliveryStore) MarkFailed(id int64, backoff time.Duration, maxAttempts int) error {
+	row := s.db.QueryRow("SELECT attempts FROM delivery_events WHERE id = ?", id)
+	var attempts int
+	if err := row.Scan(&attempts); err != nil {
+		return err
+	}
+	attempts++
+	status := "pending"
+	if attempts >= maxAttempts {
+		status = "dead"
+	}
+	_, err := s.db.Exec(
+		"UPDATE delivery_events SET status = ?, attempts = ?, next_attempt_at = ? WHERE id = ?",
+		status, attempts, time.Now().Add(backoff), id,
+	)
+	return err
+}
+
+// Requeue 把一条死信事件重新排回 pending 状态，供管理接口手动重试。
+func (s *DeliveryStore) Requeue(id int64) error {
+	_, err := s.db.Exec(
+		"UPDATE delivery_events SET status = 'pending', attempts = 0, next_attempt_at = ? WHERE id = ?",
+		time.Now(), id,
+	)
+	return err
+}
@@ -0,0 +1,198 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"books-vector-api/log"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Event 是写入 outbox 表的一行，记录 NewTask/PlanningTaskAnswer/InterruptTask 的
+// 每一次调用，使规划循环在进程崩溃重启后仍然可以知道哪些任务没有跑完。
+type Event struct {
+	ID             int64
+	TaskID         string
+	Type           string // "new_task" | "answer" | "interrupt"
+	PayloadJSON    string
+	Status         string // "pending" | "done" | "failed"
+	Attempts       int
+	IdempotencyKey string
+	CreatedAt      time.Time
+}
+
+// EventStore 是 outbox 事件的 SQLite 持久化实现。
+type EventStore struct {
+	db *sql.DB
+}
+
+// taskEventStore 是进程内唯一的 outbox 事件存储，由 InitTaskOutbox 在启动时赋值，
+// 未调用 InitTaskOutbox 时保持为 nil，NewTask 等函数会跳过持久化直接按老逻辑运行。
+var taskEventStore *EventStore
+
+// InitTaskOutbox 打开（或创建）dbPath 指向的 SQLite 文件并确保 outbox_events 表存在。
+func InitTaskOutbox(dbPath string) error {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return err
+	}
+	schema := `CREATE TABLE IF NOT EXISTS outbox_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id TEXT NOT NULL,
+		type TEXT NOT NULL,
+		payload_json TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		idempotency_key TEXT NOT NULL UNIQUE,
+		created_at DATETIME NOT NULL
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	taskEventStore = &EventStore{db: db}
+	return nil
+}
+
+// Append 写入一条待处理事件，idempotencyKey 重复时视为同一次调用，直接忽略，
+// 这样客户端因网络问题重发同一个请求不会被重复执行。
+func (s *EventStore) Append(taskID, eventType string, payload interface{}, idempotencyKey string) (int64, error) {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.db.Exec(
+		`INSERT OR IGNORE INTO outbox_events (task_id, type, payload_json, status, idempotency_key, created_at)
+		 VALUES (?, ?, ?, 'pending', ?, ?)`,
+		taskID, eventType, string(payloadBytes), idempotencyKey, time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		// INSERT OR IGNORE 因为 idempotency_key 冲突而跳过了写入，找回已存在的那一行。
+		row := s.db.QueryRow("SELECT id FROM outbox_events WHERE idempotency_key = ?", idempotencyKey)
+		if err := row.Scan(&id); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// MarkDone 把事件标记为已处理完成。
+func (s *EventStore) MarkDone(id int64) error {
+	_, err := s.db.Exec("UPDATE outbox_events SET status = 'done' WHERE id = ?", id)
+	return err
+}
+
+// MarkFailed 记录一次处理失败，attempts 达到 maxAttempts 后不再重试。
+func (s *EventStore) MarkFailed(id int64, maxAttempts int) error {
+	row := s.db.QueryRow("SELECT attempts FROM outbox_events WHERE id = ?", id)
+	var attempts int
+	if err := row.Scan(&attempts); err != nil {
+		return err
+	}
+	attempts++
+	status := "pending"
+	if attempts >= maxAttempts {
+		status = "failed"
+	}
+	_, err := s.db.Exec("UPDATE outbox_events SET status = ?, attempts = ? WHERE id = ?", status, attempts, id)
+	return err
+}
+
+// PendingEvents 按创建时间顺序取回所有还没处理完的事件，用于启动时重放。
+func (s *EventStore) PendingEvents() ([]Event, error) {
+	rows, err := s.db.Query(
+		`SELECT id, task_id, type, payload_json, status, attempts, idempotency_key, created_at
+		 FROM outbox_events WHERE status = 'pending' ORDER BY created_at ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Type, &e.PayloadJSON, &e.Status, &e.Attempts, &e.IdempotencyKey, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// RunEventWorker 每隔 interval 轮询一次 taskEventStore 里还没处理完的事件，按
+// handlers[e.Type] 重试；重试失败则计入 attempts，达到 maxAttempts 后标记为 failed 不再
+// 重试。和 ReplayPendingTasks 处理的是同一张表但职责不同：ReplayPendingTasks 只在启动时
+// 清理上一个进程遗留、注定没法恢复的 new_task（taskManager 里的状态和它绑定的 SSE 连接
+// 都已经随进程退出没了）；RunEventWorker 在当前进程里常驻轮询，负责 answer/interrupt 这类
+// 可以对仍然存活的 taskManager 重放的事件——它们卡在 pending 通常是因为处理和
+// MarkDone 之间进程发生过短暂失败，taskManager 里的任务状态还在，重放一次就能追上。
+func RunEventWorker(ctx context.Context, interval time.Duration, maxAttempts int, handlers map[string]func(Event) error) {
+	if taskEventStore == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			events, err := taskEventStore.PendingEvents()
+			if err != nil {
+				logError("拉取待处理事件失败", log.Err(err))
+				continue
+			}
+			for _, e := range events {
+				handler, ok := handlers[e.Type]
+				if !ok {
+					continue
+				}
+				if err := handler(e); err != nil {
+					logWarn("重试事件失败", log.String("taskID", e.TaskID), log.String("type", e.Type), log.Err(err))
+					if markErr := taskEventStore.MarkFailed(e.ID, maxAttempts); markErr != nil {
+						logError("标记事件失败状态出错", log.Err(markErr))
+					}
+					continue
+				}
+				if err := taskEventStore.MarkDone(e.ID); err != nil {
+					logError("标记事件完成状态出错", log.Err(err))
+				}
+			}
+		}
+	}
+}
+
+// ReplayPendingTasks 在启动时调用，找出上次进程退出前还没跑完的 new_task 事件。
+// SSE 连接本身没法跨进程重启存活，所以这里做的是诚实的 best-effort：把任务标记为
+// failed 并打日志，提醒有一个任务在服务器崩溃时还没问到答案，而不是假装能把流式
+// 响应续上。
+func ReplayPendingTasks() error {
+	if taskEventStore == nil {
+		return nil
+	}
+	events, err := taskEventStore.PendingEvents()
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if e.Type != "new_task" {
+			continue
+		}
+		logWarn("发现未完成的任务，原 SSE 连接已断开，标记为失败",
+			log.String("taskID", e.TaskID), log.String("createdAt", e.CreatedAt.Format(time.RFC3339)))
+		if err := taskEventStore.MarkFailed(e.ID, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"books-vector-api/log"
+)
+
+// Embedder 抽象了文本向量化的能力，使上层逻辑不再绑定某一个具体的模型服务商，
+// 方便在 Ollama / OpenAI 兼容接口 / 现有 ModelProviderURI 之间切换。
+type Embedder interface {
+	// Embed 对一批文本生成向量，返回顺序与输入一致。
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Dim 返回该 Embedder 产出的向量维度。
+	Dim() int
+}
+
+// EmbedderConfig 汇总构造任意 Embedder 实现所需的参数，对应 config.json 里的
+// EmbeddingProvider/EmbeddingModel/EmbeddingBatchSize/EmbeddingDim 字段。
+type EmbedderConfig struct {
+	Provider  string // "ollama" | "openai" | "provider_uri"
+	URL       string
+	Model     string
+	APIKey    string
+	BatchSize int
+	Dim       int
+}
+
+// NewEmbedder 根据 cfg.Provider 构造具体的 Embedder，并包上批量拆分与重试逻辑。
+func NewEmbedder(cfg EmbedderConfig) Embedder {
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 16
+	}
+
+	var inner Embedder
+	switch cfg.Provider {
+	case "openai":
+		inner = &openAICompatEmbedder{url: cfg.URL, apiKey: cfg.APIKey, model: cfg.Model, dim: cfg.Dim}
+	case "provider_uri":
+		inner = &openAICompatEmbedder{url: cfg.URL, apiKey: cfg.APIKey, model: cfg.Model, dim: cfg.Dim}
+	default:
+		inner = &ollamaEmbedder{url: cfg.URL, model: cfg.Model, dim: cfg.Dim}
+	}
+
+	return &retryingEmbedder{
+		inner:      &batchingEmbedder{inner: inner, batchSize: batchSize},
+		maxRetries: 3,
+		baseDelay:  500 * time.Millisecond,
+	}
+}
+
+// batchingEmbedder 把任意大小的文本列表拆分成固定大小的批次再交给内层 Embedder，
+// 这样调用方不用关心底层服务商的单次请求上限。
+type batchingEmbedder struct {
+	inner     Embedder
+	batchSize int
+}
+
+func (b *batchingEmbedder) Dim() int { return b.inner.Dim() }
+
+func (b *batchingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	result := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += b.batchSize {
+		end := start + b.batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		embeddings, err := b.inner.Embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, embeddings...)
+	}
+	return result, nil
+}
+
+// retryingEmbedder 在底层请求遇到 429/5xx 或网络错误时按指数退避重试。
+type retryingEmbedder struct {
+	inner      Embedder
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+func (r *retryingEmbedder) Dim() int { return r.inner.Dim() }
+
+func (r *retryingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var lastErr error
+	delay := r.baseDelay
+	for attempt := 0; attempt <= r.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		embeddings, err := r.inner.Embed(ctx, texts)
+		if err == nil {
+			return embeddings, nil
+		}
+		lastErr = err
+
+		var retryable *retryableError
+		if !asRetryableError(err, &retryable) {
+			return nil, err
+		}
+		logWarn("embedding 请求失败，准备重试", log.Int("attempt", attempt), log.Err(err))
+	}
+	return nil, lastErr
+}
+
+// retryableError 标记一个可以安全重试的失败请求（HTTP 429 或 5xx）。
+type retryableError struct {
+	status int
+	err    error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+func asRetryableError(err error, target **retryableError) bool {
+	if re, ok := err.(*retryableError); ok {
+		*target = re
+		return true
+	}
+	return false
+}
+
+func checkRetryableStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return &retryableError{status: resp.StatusCode, err: fmt.Errorf("embedding 服务返回 %d: %s", resp.StatusCode, string(bodyBytes))}
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("embedding 服务返回 %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
+
+// ollamaEmbedder 对接 Ollama 的 /api/embeddings 接口，该接口一次只接受一段文本。
+type ollamaEmbedder struct {
+	url   string
+	model string
+	dim   int
+}
+
+func (o *ollamaEmbedder) Dim() int { return o.dim }
+
+func (o *ollamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		reqBody, _ := json.Marshal(map[string]string{"model": o.model, "prompt": text})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, &retryableError{err: err}
+		}
+		if err := checkRetryableStatus(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var response struct {
+			Embedding []float32 `json:"embedding"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = response.Embedding
+	}
+	return embeddings, nil
+}
+
+// openAICompatEmbedder 对接 OpenAI 兼容的 /v1/embeddings 接口，原生支持一次传入多段文本。
+type openAICompatEmbedder struct {
+	url    string
+	apiKey string
+	model  string
+	dim    int
+}
+
+func (e *openAICompatEmbedder) Dim() int { return e.dim }
+
+func (e *openAICompatEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	if err := checkRetryableStatus(resp); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+			Index     int       `json:"index"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, d := range response.Data {
+		if d.Index >= 0 && d.Index < len(embeddings) {
+			embeddings[d.Index] = d.Embedding
+		}
+	}
+	return embeddings, nil
+}
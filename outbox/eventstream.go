@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EventStream 封装 SSE 帧的写入，统一 id/event/data 格式，并提供心跳，
+// 供 /chat/stream、/task/stream 等流式接口复用，替代各处手写的 fmt.Fprintf 拼帧。
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	mu      sync.Mutex
+	nextID  int64
+	done    chan struct{}
+	closed  bool
+}
+
+// NewEventStream 给 w 设置好 SSE 响应头并返回一个可以写事件的 EventStream。
+func NewEventStream(w http.ResponseWriter) (*EventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming 不支持")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	return &EventStream{w: w, flusher: flusher, done: make(chan struct{})}, nil
+}
+
+// Send 写入一帧 SSE 事件，data 会被序列化为 JSON 作为 data 字段的内容。
+func (es *EventStream) Send(eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	fmt.Fprintf(es.w, "id: %d\n", es.nextID)
+	fmt.Fprintf(es.w, "event: %s\n", eventType)
+	fmt.Fprintf(es.w, "data: %s\n\n", payload)
+	es.flusher.Flush()
+	es.nextID++
+	return nil
+}
+
+// Heartbeat 启动一个后台 goroutine，每隔 interval 发送一次 SSE 注释行（": heartbeat"），
+// 防止反向代理因为连接空闲而提前断开。调用 Close 后心跳会停止。
+func (es *EventStream) Heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-es.done:
+				return
+			case <-ticker.C:
+				es.mu.Lock()
+				fmt.Fprint(es.w, ": heartbeat\n\n")
+				es.flusher.Flush()
+				es.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Close 停止心跳 goroutine，应当在处理完这次请求后用 defer 调用。
+func (es *EventStream) Close() {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if !es.closed {
+		close(es.done)
+		es.closed = true
+	}
+}
@@ -1,8 +1,8 @@
 package outbox
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
@@ -10,14 +10,13 @@ import (
 	"net/http"
 	"strings"
 	"time"
-)
 
-const (
-	SIGNAL_STOP = "stop"
+	"books-vector-api/internal/linkedhashmap"
+	"books-vector-api/log"
+	"books-vector-api/outbox/sse"
 )
 
 var (
-	globalSession  *Session
 	FlusherWriter  http.Flusher
 	ResponseWriter http.ResponseWriter
 
@@ -28,16 +27,38 @@ var (
 	ModelProviderAPIKey string
 )
 
-func InitGlobalSession() {
-	globalSession = NewSession()
-}
-
 func InitModelProvider(providerURI string, providerModel string, providerAPIKey string) {
 	ModelProviderURI = providerURI
 	ModelProviderModel = providerModel
 	ModelProviderAPIKey = providerAPIKey
 }
 
+// Sessions 是进程内持久化会话历史的入口，由 main.go 在 init() 里通过 InitSessionStore
+// 注入，和 InitModelProvider 一样的接线方式。未注入时为 nil，NewTask/NewStreamTask 会
+// 跳过持久化：taskManager 已经能让任务正常跑完，Sessions 只是多一份跨重启的历史记录。
+var Sessions *Session
+
+// RecallMemoryFunc/RememberTurnFunc 由 main.go 在 init() 里注入 memory.go 里的
+// recallContext/rememberTurn，让 NewTask 也能像 ChatStream 一样
+// 召回/记录对话记忆，和 VectorSearchFunc 一样的接线方式；未注入时直接跳过，不影响既有的
+// 规划循环。
+var (
+	RecallMemoryFunc func(ctx context.Context, sessionID, query string) (string, error)
+	RememberTurnFunc func(ctx context.Context, sessionID, userID, role, content string) error
+)
+
+// InitSessionStore 用 store 作为持久化后端创建 Sessions。
+func InitSessionStore(store SessionStore) {
+	Sessions = NewSession(store)
+}
+
+// recordSessionEntry 是 Sessions 为 nil 时的安全跳过包装，避免每个调用点都判空。
+func recordSessionEntry(taskID string, entry MessageType) {
+	if Sessions != nil {
+		Sessions.AddEntry(taskID, entry)
+	}
+}
+
 type OutMessage struct {
 	Type     string `json:"type"`
 	Payload  string `json:"payload"`
@@ -62,19 +83,39 @@ func GenerateUUID() string {
 		u[10:16])
 }
 
-func waitUntil(timeout time.Duration, codition func() bool) {
-	start := time.Now()
-	for {
-		fmt.Println("等待中...")
-		out := codition()
-		if out {
-			return
+// doWithRetry 对规划循环里请求大模型的调用做指数退避重试，沿用 embedder.go 里
+// retryingEmbedder 对 429/5xx 的判断方式。ctx 取消时立即放弃重试，不再傻等 delay 走完。
+func doWithRetry(ctx context.Context, do func() (*http.Response, error), maxRetries int, baseDelay time.Duration) (*http.Response, error) {
+	var lastErr error
+	delay := baseDelay
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			logWarn("大模型请求失败，准备重试", log.Int("attempt", attempt), log.Err(lastErr))
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		resp, err := do()
+		if err != nil {
+			lastErr = err
+			continue
 		}
-		if time.Since(start) > timeout {
-			return
+		if err := checkRetryableStatus(resp); err != nil {
+			resp.Body.Close()
+			var retryable *retryableError
+			if !asRetryableError(err, &retryable) {
+				return nil, err
+			}
+			lastErr = err
+			continue
 		}
-		time.Sleep(2000 * time.Millisecond)
+		return resp, nil
 	}
+	return nil, lastErr
 }
 
 func PrintOut(eventType string, data string) {
@@ -99,21 +140,73 @@ var (
 
 func NewTask(w http.ResponseWriter, r *http.Request) {
 
-	fmt.Println("创建新任务...")
+	logInfo("创建新任务")
 	id = 0 // 重置 id
 	// 从 body 中读取请求体
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, "读取请求体失败", http.StatusBadRequest)
 	}
-	// 解析请求体，取出 text
+	// 解析请求体，取出 text；sessionId 是客户端在多次 /task 调用之间自己维护的会话标识，
+	// taskID 每次都是新生成的 UUID，不能拿来当记忆的 key（见下面的召回/记录）。
 	var ChatRequest struct {
-		Text string `json:"text"`
+		Text      string `json:"text"`
+		SessionID string `json:"sessionId"`
 	}
 	if err := json.Unmarshal(body, &ChatRequest); err != nil {
 		http.Error(w, "解析请求体失败", http.StatusBadRequest)
 	}
-	// 每个新任务都覆盖全局会话，暂时支持单任务
+
+	taskID := GenerateUUID()
+	logInfo("分配任务 ID", log.String("taskID", taskID))
+
+	// 落一条 outbox 事件再处理，崩溃重启后 ReplayPendingTasks 能知道这个任务没跑完。
+	var eventID int64
+	if taskEventStore != nil {
+		eventID, _ = taskEventStore.Append(taskID, "new_task", ChatRequest, taskID)
+		defer func() {
+			taskEventStore.MarkDone(eventID)
+		}()
+	}
+
+	// 有 sessionId 时才召回/记录对话记忆，和 chat.go 的 Chat/ChatStream 一致；taskID 每次
+	// 请求都不同，不能代替 sessionId。originalText 留着存成 "user" 这一轮的记忆，传给
+	// recursivePlanningTask 的 plannedText 才是召回结果拼接后的版本——避免把召回出来的
+	// 历史上下文也当成这一轮说的话存回去，越存越重复。
+	originalText := ChatRequest.Text
+	plannedText := ChatRequest.Text
+	if ChatRequest.SessionID != "" {
+		if RecallMemoryFunc != nil {
+			if memoryContext, err := RecallMemoryFunc(r.Context(), ChatRequest.SessionID, originalText); err != nil {
+				logWarn("召回对话记忆失败", log.Err(err))
+			} else if memoryContext != "" {
+				plannedText = memoryContext + "\n\n" + originalText
+			}
+		}
+		if RememberTurnFunc != nil {
+			if err := RememberTurnFunc(r.Context(), ChatRequest.SessionID, "", "user", originalText); err != nil {
+				logWarn("记录对话记忆失败", log.Err(err))
+			}
+		}
+	}
+
+	// 每个任务在 taskManager 里登记自己的 context，InterruptTask 取消它即可让下面的
+	// 模型请求、SSE 读取和 answer 等待立即退出；多个任务各自持有 taskID，互不影响。
+	ctx, cancel := taskManager.Start(taskID)
+	defer cancel()
+	defer taskManager.Finish(taskID)
+
+	// 请求方的连接断开时一并取消任务。
+	ctx, cancelOnClientGone := context.WithCancel(ctx)
+	defer cancelOnClientGone()
+	go func() {
+		select {
+		case <-r.Context().Done():
+			cancelOnClientGone()
+		case <-ctx.Done():
+		}
+	}()
+
 	ResponseWriter = w
 	// 添加 text/event-stream 响应头
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -125,15 +218,11 @@ func NewTask(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Streaming 不支持", http.StatusInternalServerError)
 	}
 
-	// data := OutMessage{
-	// 	Type:     "text",
-	// 	Payload:  "连接成功，请稍后",
-	// 	ActionID: "",
-	// }
-	// dataBytes, _ := json.Marshal(data)
-	// PrintOut("200", string(dataBytes))
+	// 把 taskID 告知客户端，/planning/task/answer 和 /planning/task/interrupt 都要带上它。
+	started := OutMessage{Type: "task_started", Payload: taskID, ActionID: ""}
+	startedBytes, _ := json.Marshal(started)
+	PrintOut("200", string(startedBytes))
 
-	// taskDone := false
 	breakDone := false
 	doneMessage := ""
 
@@ -142,9 +231,9 @@ func NewTask(w http.ResponseWriter, r *http.Request) {
 	currentInnerLoop = 0
 
 	for {
-		fmt.Println("当前循环次数: ", currentLoop)
+		logInfo("规划循环", log.String("taskID", taskID), log.Int("loop", currentLoop))
 
-		if ok := CheckInterrupt(); ok {
+		if ctx.Err() != nil {
 			data := OutMessage{
 				Type:     "statusText",
 				Payload:  "任务中断: 用户中断",
@@ -178,8 +267,8 @@ func NewTask(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// 执行用于任务计划的递归函数
-		planningTaskCompletion := recursivePlanningTask(ChatRequest.Text)
-		fmt.Println("planningIsEnd: ", planningTaskCompletion)
+		planningTaskCompletion := recursivePlanningTask(ctx, taskID, plannedText)
+		logInfo("规划循环结束", log.String("taskID", taskID), log.Any("completion", planningTaskCompletion))
 		// 如果 planningIsEnd 为 true，则退出循环
 		if planningTaskCompletion.Break {
 			breakDone = true
@@ -191,6 +280,12 @@ func NewTask(w http.ResponseWriter, r *http.Request) {
 		}
 		currentLoop++
 	}
+
+	if ChatRequest.SessionID != "" && RememberTurnFunc != nil && doneMessage != "" {
+		if err := RememberTurnFunc(r.Context(), ChatRequest.SessionID, "", "assistant", doneMessage); err != nil {
+			logWarn("记录对话记忆失败", log.Err(err))
+		}
+	}
 }
 
 type ProviderStreamResponse struct {
@@ -198,6 +293,10 @@ type ProviderStreamResponse struct {
 		Delta struct {
 			Content   string `json:"content"`
 			ToolCalls []struct {
+				// Index 标识这个增量属于哪一次并行的 tool_call，同一个 tool_call 的参数
+				// 会分散在多个 delta 里，必须按 Index 累加而不是假设只有一个 tool_call。
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
 				Function struct {
 					Arguments string `json:"arguments"`
 					Name      string `json:"name"`
@@ -213,7 +312,7 @@ type RecursiveTaskCompletion struct {
 	Break bool   `json:"break"`
 }
 
-func recursivePlanningTask(text string) RecursiveTaskCompletion {
+func recursivePlanningTask(ctx context.Context, taskID string, text string) RecursiveTaskCompletion {
 	// 限制内层循环
 	if currentInnerLoop >= maxInnerLoop {
 		return RecursiveTaskCompletion{
@@ -222,7 +321,7 @@ func recursivePlanningTask(text string) RecursiveTaskCompletion {
 		}
 	}
 
-	if ok := CheckInterrupt(); ok {
+	if ctx.Err() != nil {
 		return RecursiveTaskCompletion{
 			Text:  "任务中断: 用户中断",
 			Break: true,
@@ -249,31 +348,13 @@ func recursivePlanningTask(text string) RecursiveTaskCompletion {
 		"tool_choice": Tool_Use_Case_Prompt["tool_choice"],
 	})
 	if err != nil {
-		fmt.Println("创建请求体失败: ", err)
+		logError("创建请求体失败", log.Err(err))
 		return RecursiveTaskCompletion{
 			Text:  "创建请求体失败: " + err.Error(),
 			Break: true,
 		}
 	}
-	fmt.Println("🌍 大模型请求体: ", string(modelRequestBody), "\n\n", "大模型请求地址: ", ModelProviderURI, "\n\n", "")
-	// Planning Task: 请求 openrouter 并传入 InteractivePlanningSystemPrompt
-	// 将返回的内容写入会话
-	req, _ := http.NewRequest("POST", ModelProviderURI, bytes.NewBuffer(modelRequestBody))
-	req.Header.Set("Authorization", "Bearer "+ModelProviderAPIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	// 发送请求
-	// 将返回的内容写入会话
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		// http.Error(ResponseWriter, "调用大模型失败: "+err.Error(), http.StatusInternalServerError)
-		return RecursiveTaskCompletion{
-			Text:  "调用大模型失败: " + err.Error(),
-			Break: true,
-		}
-	}
-	defer resp.Body.Close()
+	logInfo("请求大模型", log.Int("innerLoop", currentInnerLoop), log.String("uri", ModelProviderURI))
 
 	// 存储工具调用信息的变量
 	toolCallDetected := false
@@ -281,57 +362,69 @@ func recursivePlanningTask(text string) RecursiveTaskCompletion {
 
 	// 为本次消息创建一个唯一ID
 	textMessageID := GenerateUUID()
-	// 流式返回
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		// 在流式输出找到中断信号
-		// if ok := CheckInterrupt(); ok {
-		// 	return RecursiveTaskCompletion{
-		// 		Text: "任务中断: 用户中断",
-		// 		Break: true,
-		// 	}
-		// }
-
-		line := scanner.Text()
-		// fmt.Println("line: ", line)
-		if len(line) > 6 && line[:6] == "data: " {
-			data := line[6:]
-			if data == "[DONE]" {
-				break
+
+	// Planning Task: 请求 openrouter 并传入 InteractivePlanningSystemPrompt，流式返回写入
+	// 会话。用 sse.Client 代替手写的 doWithRetry+decoder 循环：连接中断时带着
+	// Last-Event-ID 自动重连并指数退避，而不是整次调用直接判失败。
+	sseClient := sse.NewClient()
+	streamErr := sseClient.Stream(ctx, func(reqCtx context.Context, lastEventID string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(reqCtx, "POST", ModelProviderURI, bytes.NewBuffer(modelRequestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+ModelProviderAPIKey)
+		req.Header.Set("Content-Type", "application/json")
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		return req, nil
+	}, func(event sse.Event) error {
+		if event.Data == "[DONE]" {
+			return io.EOF
+		}
+		var orResp ProviderStreamResponse
+		if err := json.Unmarshal([]byte(event.Data), &orResp); err != nil || len(orResp.Choices) == 0 {
+			return nil
+		}
+		content := orResp.Choices[0].Delta.Content
+		if content != "" && content != "\n" {
+			meta := linkedhashmap.New[string, interface{}]()
+			meta.Set("id", textMessageID)
+			payload := MessageType{
+				Type: "text",
+				Payload: PayloadType{
+					Content: content,
+					Meta:    meta,
+				},
+			}
+			payloadStr, _ := json.Marshal(payload)
+			data := OutMessage{
+				Type:     "text",
+				Payload:  string(payloadStr),
+				ActionID: "",
 			}
-			var orResp ProviderStreamResponse
-			if err := json.Unmarshal([]byte(data), &orResp); err == nil {
-				if len(orResp.Choices) > 0 {
-					content := orResp.Choices[0].Delta.Content
-					if content != "" && content != "\n" {
-						payload := MessageType{
-							Type: "text",
-							Payload: PayloadType{
-								Content: content,
-								Meta: map[string]interface{}{
-									"id": textMessageID,
-								},
-							},
-						}
-						payloadStr, _ := json.Marshal(payload)
-						data := OutMessage{
-							Type:     "text",
-							Payload:  string(payloadStr),
-							ActionID: "",
-						}
-						dataBytes, _ := json.Marshal(data)
-						PrintOut("200", string(dataBytes))
-					}
-					// fmt.Println("><检测工具调用", orResp.Choices[0].Delta.ToolCalls)
-					// 累加工具调用参数
-					if len(orResp.Choices[0].Delta.ToolCalls) > 0 {
-						fmt.Println("><检测到工具调用", orResp.Choices[0].Delta.ToolCalls[0].Function.Arguments)
-						toolCallDetected = true
-						toolCallArguments.WriteString(orResp.Choices[0].Delta.ToolCalls[0].Function.Arguments)
-					}
-				}
+			dataBytes, _ := json.Marshal(data)
+			PrintOut("200", string(dataBytes))
+		}
+		// 累加工具调用参数
+		if len(orResp.Choices[0].Delta.ToolCalls) > 0 {
+			logInfo("检测到工具调用", log.String("arguments", orResp.Choices[0].Delta.ToolCalls[0].Function.Arguments))
+			toolCallDetected = true
+			toolCallArguments.WriteString(orResp.Choices[0].Delta.ToolCalls[0].Function.Arguments)
+		}
+		return nil
+	})
+	if streamErr != nil {
+		if ctx.Err() != nil {
+			return RecursiveTaskCompletion{
+				Text:  "任务中断: 用户中断",
+				Break: true,
 			}
 		}
+		return RecursiveTaskCompletion{
+			Text:  "调用大模型失败: " + streamErr.Error(),
+			Break: true,
+		}
 	}
 
 	if !toolCallDetected {
@@ -354,15 +447,15 @@ func recursivePlanningTask(text string) RecursiveTaskCompletion {
 	// 将工具调用参数写入会话
 	// 添加一个 ask 消息
 	actionID := GenerateUUID()
+	askMeta := linkedhashmap.New[string, interface{}]()
+	askMeta.Set("answer", false)
+	askMeta.Set("reason", "")
+	askMeta.Set("id", actionID)
 	payload := MessageType{
 		Type: "ask",
 		Payload: PayloadType{
 			Content: toolArgs.Question,
-			Meta: map[string]interface{}{
-				"answer": false,
-				"reason": "",
-				"id":     actionID,
-			},
+			Meta:    askMeta,
 		},
 		ActionID: actionID,
 	}
@@ -374,52 +467,39 @@ func recursivePlanningTask(text string) RecursiveTaskCompletion {
 	}
 	dataBytes, _ := json.Marshal(data)
 	PrintOut("200", string(dataBytes))
-	globalSession.AddEntry("ask", payload)
-
-	lastEntry := MessageType{}
-	waitUntil(60*time.Second, func() bool {
-		if ok := CheckInterrupt(); ok {
-			return true
-		}
-
-		entries, exists := globalSession.GetEntries("ask")
-		if !exists || len(entries) == 0 {
-			return false
-		}
+	recordSessionEntry(taskID, payload)
 
-		for i := len(entries) - 1; i >= 0; i-- {
-			if entries[i].ActionID == actionID {
-				entry := entries[i]
-				fmt.Println("waiting entry: ", entry.ActionID)
-				// 仅当 answer 为 true 时才返回 true
-				if answer, ok := entry.Payload.Meta["answer"].(bool); ok && answer {
-					lastEntry = entry
-					return true
-				}
-				return false
+	// 通过 taskManager 按 taskID+actionID 等待回答，ctx 取消（用户中断或连接断开）或
+	// 60 秒超时都会立即解除阻塞，不再靠轮询一份共享状态。
+	waitCtx, cancelWait := context.WithTimeout(ctx, 60*time.Second)
+	defer cancelWait()
+	answer, err := taskManager.WaitForAnswer(waitCtx, taskID, actionID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return RecursiveTaskCompletion{
+				Text:  "任务中断: 用户中断",
+				Break: true,
 			}
 		}
-
-		return false
-	})
-
-	// 如果 lastEntry.Type 为空说明超时
-	if lastEntry.Type == "" {
 		return RecursiveTaskCompletion{
 			Text:  "回答超时",
 			Break: true,
 		}
 	}
-	// 如果 answer 为 true 且 reason 为空，说明用户同意，可继续
-	if lastEntry.Payload.Meta["answer"] == true && lastEntry.Payload.Meta["reason"] == "" {
+
+	logInfo("收到 ask 回答", log.String("actionID", actionID))
+	recordSessionEntry(taskID, answer)
+	reasonValue, _ := answer.Payload.Meta.Get("reason")
+	reason, _ := reasonValue.(string)
+	// reason 为空说明用户同意但没有补充理由，和老协议一样视为用户取消
+	if reason == "" {
 		return RecursiveTaskCompletion{
 			Text:  "用户取消",
 			Break: true,
 		}
 	}
-	reason := lastEntry.Payload.Meta["reason"].(string)
 	currentInnerLoop++
-	return recursivePlanningTask(reason)
+	return recursivePlanningTask(ctx, taskID, reason)
 }
 
 func PlanningTaskAnswer(w http.ResponseWriter, r *http.Request) {
@@ -433,72 +513,108 @@ func PlanningTaskAnswer(w http.ResponseWriter, r *http.Request) {
 	var ChatRequest struct {
 		Text     string `json:"text"`
 		ActionID string `json:"actionID"`
+		TaskID   string `json:"taskID"`
 	}
 	if err := json.Unmarshal(body, &ChatRequest); err != nil {
 		http.Error(w, "解析请求体失败", http.StatusBadRequest)
 		return
 	}
 
-	entries, _ := globalSession.GetEntries("ask")
-	// fmt.Println("entries: ", entries)
-	if (entries == nil) || (len(entries) == 0) {
-		http.Error(w, "没有找到相应的 ask 消息", http.StatusBadRequest)
-	} else {
-		lastEntry := entries[len(entries)-1]
-
-		askMessage := MessageType{
-			Type: "ask",
-			Payload: PayloadType{
-				Content: lastEntry.Payload.Content,
-				Meta: map[string]interface{}{
-					"answer": true,
-					"reason": ChatRequest.Text,
-				},
-			},
-			ActionID: ChatRequest.ActionID,
-		}
-		globalSession.AddEntry("ask", askMessage)
+	if taskEventStore != nil {
+		idempotencyKey := ChatRequest.TaskID + "-" + ChatRequest.ActionID + "-answer"
+		eventID, _ := taskEventStore.Append(ChatRequest.TaskID, "answer", ChatRequest, idempotencyKey)
+		defer func() {
+			taskEventStore.MarkDone(eventID)
+		}()
 	}
-}
 
-// 客户端发起中断信号
-func InterruptTask(w http.ResponseWriter, r *http.Request) {
-	askMessage := MessageType{
-		Type: "signal",
+	// /task 和 /task/stream 现在用同一个 taskManager，按各自的 taskID 投递回答即可。
+	answerMeta := linkedhashmap.New[string, interface{}]()
+	answerMeta.Set("answer", true)
+	answerMeta.Set("reason", ChatRequest.Text)
+	if !taskManager.Answer(ChatRequest.TaskID, ChatRequest.ActionID, MessageType{
+		Type: "ask",
 		Payload: PayloadType{
-			Content: "",
-			Meta: map[string]interface{}{
-				"action": SIGNAL_STOP,
-			},
+			Meta: answerMeta,
 		},
-		ActionID: "",
+		ActionID: ChatRequest.ActionID,
+	}) {
+		http.Error(w, "没有找到对应的任务或该回答已经被处理", http.StatusBadRequest)
+		return
 	}
-	globalSession.AddEntry("ask", askMessage)
-	// 返回 200
-	w.WriteHeader(http.StatusOK)
-	// 返回 json
 	w.Header().Set("Content-Type", "application/json")
-	response := map[string]string{
-		"message": "中断信号已发送",
-		"status":  "200",
+	json.NewEncoder(w).Encode(map[string]string{"message": "已回答"})
+}
+
+// 客户端发起中断信号：取消 taskID 对应任务的 context，阻塞中的模型请求/SSE 读取/
+// answer 等待都会立即返回。
+func InterruptTask(w http.ResponseWriter, r *http.Request) {
+	var interruptRequest struct {
+		TaskID string `json:"taskID"`
+	}
+	if body, err := io.ReadAll(r.Body); err == nil && len(body) > 0 {
+		json.Unmarshal(body, &interruptRequest)
 	}
-	json.NewEncoder(w).Encode(response)
+
+	if taskEventStore != nil {
+		idempotencyKey := interruptRequest.TaskID + "-interrupt"
+		eventID, _ := taskEventStore.Append(interruptRequest.TaskID, "interrupt", interruptRequest, idempotencyKey)
+		defer func() {
+			taskEventStore.MarkDone(eventID)
+		}()
+	}
+
+	if !taskManager.Cancel(interruptRequest.TaskID) {
+		http.Error(w, "没有找到对应的任务", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "中断信号已发送", "status": "200"})
 }
 
-// 执行后从最新一条信息检查 signal 类型的消息，如果有则中断
-func CheckInterrupt() bool {
-	entries, _ := globalSession.GetEntries("ask")
-	if (entries == nil) || (len(entries) == 0) {
-		return false
+// DefaultEventHandlers 返回 RunEventWorker 用来重放 answer/interrupt 事件的默认实现：
+// 两者都是把原始请求体重新喂给处理该类型事件时调用的同一个 taskManager 方法，taskManager
+// 的状态在本进程内一直存活，卡在 pending 多半是处理完成和 MarkDone 之间出现过短暂失败。
+// new_task 不在这里——它绑定的 SSE 连接随进程退出已经不在了，没法重放，交给
+// ReplayPendingTasks 在启动时诚实地标记为失败。
+func DefaultEventHandlers() map[string]func(Event) error {
+	return map[string]func(Event) error{
+		"answer":    retryAnswerEvent,
+		"interrupt": retryInterruptEvent,
 	}
+}
 
-	lastEntry := entries[len(entries)-1]
-	if lastEntry.Type == "signal" {
-		if action, ok := lastEntry.Payload.Meta["action"].(string); ok && action == SIGNAL_STOP {
-			// 删除该消息
-			globalSession.DeleteLastEntry("ask")
-			return true
-		}
+func retryAnswerEvent(e Event) error {
+	var req struct {
+		Text     string `json:"text"`
+		ActionID string `json:"actionID"`
+		TaskID   string `json:"taskID"`
+	}
+	if err := json.Unmarshal([]byte(e.PayloadJSON), &req); err != nil {
+		return err
+	}
+	answerMeta := linkedhashmap.New[string, interface{}]()
+	answerMeta.Set("answer", true)
+	answerMeta.Set("reason", req.Text)
+	if !taskManager.Answer(req.TaskID, req.ActionID, MessageType{
+		Type:     "ask",
+		Payload:  PayloadType{Meta: answerMeta},
+		ActionID: req.ActionID,
+	}) {
+		return fmt.Errorf("没有找到对应的任务或该回答已经被处理")
+	}
+	return nil
+}
+
+func retryInterruptEvent(e Event) error {
+	var req struct {
+		TaskID string `json:"taskID"`
+	}
+	if err := json.Unmarshal([]byte(e.PayloadJSON), &req); err != nil {
+		return err
+	}
+	if !taskManager.Cancel(req.TaskID) {
+		return fmt.Errorf("没有找到对应的任务")
 	}
-	return false
+	return nil
 }
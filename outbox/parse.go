@@ -1,11 +1,12 @@
 package outbox
 
 import (
-	"bufio"
-	"bytes"
 	"encoding/xml"
-	"fmt"
+	"io"
 	"strings"
+
+	"books-vector-api/log"
+	"books-vector-api/outbox/sse"
 )
 
 type ToolUse struct {
@@ -14,25 +15,21 @@ type ToolUse struct {
 	ToolUseName string   `xml:"tool_use_name"`
 }
 
+// processSSEStream 按 WHATWG SSE 规范逐帧解析 stream，而不是用 line[:6] == "data: "
+// 猜内容边界，这样多行 data: 帧不会被截断或拆散。stream 是已经完整收到的字符串而不是
+// 一个活的连接，没有可以重发的 HTTP 请求，所以这里不走 sse.Client 的重连逻辑。
 func processSSEStream(stream string) {
-	scanner := bufio.NewScanner(strings.NewReader(stream))
-	var eventData bytes.Buffer
-	var inEvent bool
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if strings.HasPrefix(line, "data: ") {
-			inEvent = true
-			eventData.WriteString(strings.TrimPrefix(line, "data: "))
-		} else if line == "" && inEvent {
-			// 空行表示事件结束
-			processEventData(eventData.String())
-			eventData.Reset()
-			inEvent = false
-		} else if inEvent {
-			eventData.WriteString(line)
+	decoder := sse.NewDecoder(strings.NewReader(stream))
+	for {
+		event, err := decoder.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			logError("解析 SSE 流失败", log.Err(err))
+			return
 		}
+		processEventData(event.Data)
 	}
 }
 
@@ -41,12 +38,10 @@ func processEventData(data string) {
 		var tool ToolUse
 		err := xml.Unmarshal([]byte(data), &tool)
 		if err != nil {
-			fmt.Println("Error parsing tool_use:", err)
+			logError("解析 tool_use 失败", log.Err(err))
 			return
 		}
 
-		fmt.Println("Parsed Tool Use:")
-		fmt.Println("Params:", tool.Params)
-		fmt.Println("Tool Name:", tool.ToolUseName)
+		logInfo("解析出 tool_use", log.String("params", tool.Params), log.String("toolName", tool.ToolUseName))
 	}
 }
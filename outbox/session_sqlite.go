@@ -0,0 +1,108 @@
+package outbox
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteSessionStore 是 SessionStore 的 SQLite 实现，entries 按 session_id 分组、按写入
+// 顺序落在一张表里，适合单机部署持久化对话历史；要跑多个副本的话应该换成 Mongo 之类
+// 的共享存储，但接口是一样的。
+type SQLiteSessionStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteSessionStore 打开（或创建）dbPath 指向的 SQLite 文件并确保表结构存在。
+func NewSQLiteSessionStore(dbPath string) (*SQLiteSessionStore, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, err
+	}
+	schema := `CREATE TABLE IF NOT EXISTS session_entries (
+		session_id TEXT NOT NULL,
+		seq        INTEGER NOT NULL,
+		entry_json TEXT NOT NULL,
+		PRIMARY KEY (session_id, seq)
+	)`
+	if _, err := db.Exec(schema); err != nil {
+		return nil, err
+	}
+	return &SQLiteSessionStore{db: db}, nil
+}
+
+func (s *SQLiteSessionStore) AddEntry(sessionID string, entry MessageType) error {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		`INSERT INTO session_entries (session_id, seq, entry_json)
+		 VALUES (?, (SELECT COALESCE(MAX(seq), -1) + 1 FROM session_entries WHERE session_id = ?), ?)`,
+		sessionID, sessionID, string(entryJSON),
+	)
+	return err
+}
+
+func (s *SQLiteSessionStore) GetEntries(sessionID string) ([]MessageType, error) {
+	rows, err := s.db.Query(
+		`SELECT entry_json FROM session_entries WHERE session_id = ? ORDER BY seq ASC`, sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []MessageType
+	for rows.Next() {
+		var entryJSON string
+		if err := rows.Scan(&entryJSON); err != nil {
+			return nil, err
+		}
+		var entry MessageType
+		if err := json.Unmarshal([]byte(entryJSON), &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLiteSessionStore) DeleteEntries(sessionID string) error {
+	_, err := s.db.Exec(`DELETE FROM session_entries WHERE session_id = ?`, sessionID)
+	return err
+}
+
+// UpdateEntry 按写入顺序里的 seq（等同内存版里的切片下标）定位要覆盖的那一行。
+func (s *SQLiteSessionStore) UpdateEntry(sessionID string, index int, entry MessageType) error {
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.Exec(
+		`UPDATE session_entries SET entry_json = ? WHERE session_id = ? AND seq = ?`,
+		string(entryJSON), sessionID, index,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("session entry not found: %s[%d]", sessionID, index)
+	}
+	return nil
+}
+
+func (s *SQLiteSessionStore) DeleteLastEntry(sessionID string) error {
+	_, err := s.db.Exec(
+		`DELETE FROM session_entries WHERE session_id = ?
+		 AND seq = (SELECT MAX(seq) FROM session_entries WHERE session_id = ?)`,
+		sessionID, sessionID,
+	)
+	return err
+}
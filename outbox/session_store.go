@@ -0,0 +1,71 @@
+package outbox
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SessionStore 持久化会话的 entries，Session 自己只管内存缓存和异步刷盘，真正的读写
+// 落在 SessionStore 上，换底层实现（内存/SQLite/Mongo）时 Session 不用跟着改。
+type SessionStore interface {
+	AddEntry(sessionID string, entry MessageType) error
+	GetEntries(sessionID string) ([]MessageType, error)
+	DeleteEntries(sessionID string) error
+	UpdateEntry(sessionID string, index int, entry MessageType) error
+	DeleteLastEntry(sessionID string) error
+}
+
+// InMemorySessionStore 是默认实现，进程重启或水平扩容时历史会丢失，仅适合本地开发。
+type InMemorySessionStore struct {
+	mu      sync.Mutex
+	entries map[string][]MessageType
+}
+
+func NewInMemorySessionStore() *InMemorySessionStore {
+	return &InMemorySessionStore{entries: make(map[string][]MessageType)}
+}
+
+func (s *InMemorySessionStore) AddEntry(sessionID string, entry MessageType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[sessionID] = append(s.entries[sessionID], entry)
+	return nil
+}
+
+func (s *InMemorySessionStore) GetEntries(sessionID string) ([]MessageType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.entries[sessionID]
+	out := make([]MessageType, len(entries))
+	copy(out, entries)
+	return out, nil
+}
+
+func (s *InMemorySessionStore) DeleteEntries(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, sessionID)
+	return nil
+}
+
+func (s *InMemorySessionStore) UpdateEntry(sessionID string, index int, entry MessageType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, ok := s.entries[sessionID]
+	if !ok || index < 0 || index >= len(entries) {
+		return fmt.Errorf("session entry not found: %s[%d]", sessionID, index)
+	}
+	entries[index] = entry
+	return nil
+}
+
+func (s *InMemorySessionStore) DeleteLastEntry(sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, ok := s.entries[sessionID]
+	if !ok || len(entries) == 0 {
+		return nil
+	}
+	s.entries[sessionID] = entries[:len(entries)-1]
+	return nil
+}
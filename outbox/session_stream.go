@@ -0,0 +1,50 @@
+package outbox
+
+import (
+	"net/http"
+	"time"
+)
+
+// SessionStreamHandler 是 /session/stream 的 http.HandlerFunc：按 sessionID 先把已有的
+// 会话历史回放一遍，再通过 Session.Subscribe 持续把新的 AddEntry/UpdateEntry 以 SSE 事件
+// 推给客户端，直到连接断开——用来在前端按 token 渲染正在进行中的 ask/answer 对话，
+// 不用轮询 /planning/task/answer 之类的接口。
+func SessionStreamHandler(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.URL.Query().Get("sessionId")
+	if sessionID == "" {
+		http.Error(w, "sessionId 不能为空", http.StatusBadRequest)
+		return
+	}
+	if Sessions == nil {
+		http.Error(w, "会话存储未初始化", http.StatusInternalServerError)
+		return
+	}
+
+	stream, err := NewEventStream(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stream.Heartbeat(15 * time.Second)
+	defer stream.Close()
+
+	entries, _ := Sessions.GetEntries(sessionID)
+	for _, entry := range entries {
+		stream.Send("message", entry)
+	}
+
+	ch, unsubscribe := Sessions.Subscribe(sessionID)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry, ok := <-ch:
+			if !ok {
+				return
+			}
+			stream.Send("message", entry)
+		}
+	}
+}
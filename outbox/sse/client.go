@@ -0,0 +1,122 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RequestFunc 根据 lastEventID（首次连接时为空字符串）构造一次 HTTP 请求，每次
+// 重连 Client 都会重新调用它，好让调用方把 Last-Event-ID 写进请求头或请求体。
+type RequestFunc func(ctx context.Context, lastEventID string) (*http.Request, error)
+
+// Client 包装 http.Client，在连接中断时按 Last-Event-ID 自动重连并做指数退避，
+// 和 outbox 里既有的 doWithRetry/retryingEmbedder 退避策略保持一致的风格。
+type Client struct {
+	HTTPClient *http.Client
+	MaxRetries int
+	BaseDelay  time.Duration
+}
+
+// NewClient 返回一个用 http.DefaultClient、最多重试 5 次、初始退避 500ms 的 Client。
+func NewClient() *Client {
+	return &Client{
+		HTTPClient: http.DefaultClient,
+		MaxRetries: 5,
+		BaseDelay:  500 * time.Millisecond,
+	}
+}
+
+// errStop 是 onEvent 主动要求终止时内部使用的哨兵错误，不会被当作需要重连的异常。
+var errStop = fmt.Errorf("sse: onEvent 主动终止")
+
+// Stream 建立连接并把解析出的每一帧事件交给 onEvent；连接意外中断时带上
+// Last-Event-ID 自动重连并做指数退避，直到流正常结束（io.EOF）、onEvent 返回
+// error、ctx 被取消，或重试次数耗尽。已经交付过事件、但流里从没带过 id 时断线不重连，
+// 直接返回错误。
+func (c *Client) Stream(ctx context.Context, newRequest RequestFunc, onEvent func(Event) error) error {
+	lastEventID := ""
+	delay := c.BaseDelay
+	receivedEvent := false
+
+	// 包一层 onEvent，只是为了记下"这次连接有没有真的交付过至少一帧"，不改变调用方看到的行为。
+	wrappedOnEvent := func(event Event) error {
+		receivedEvent = true
+		return onEvent(event)
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest(ctx, lastEventID)
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			if attempt >= c.MaxRetries {
+				return fmt.Errorf("sse 连接失败: %w", err)
+			}
+			if !sleepOrDone(ctx, delay) {
+				return ctx.Err()
+			}
+			delay *= 2
+			continue
+		}
+
+		streamErr := consume(resp.Body, wrappedOnEvent, &lastEventID)
+		resp.Body.Close()
+
+		if streamErr == nil {
+			return nil
+		}
+		if streamErr == errStop {
+			return nil
+		}
+		if receivedEvent && lastEventID == "" {
+			// 流里没带过 id，就没有 Last-Event-ID 可以续传：newRequest 重新发起的是同一个
+			// 请求体，对一个一次性生成的流（比如模型补全）来说重连只会从头重新生成一遍，
+			// onEvent 那边已经处理过的部分内容也没法干净地撤回。这种流在交付过内容之后再断线，
+			// 直接当硬失败返回，交给调用方决定要不要整体重试，而不是在这里悄悄重连导致内容
+			// 重复或者拼出半截 JSON。
+			return fmt.Errorf("sse 流中断（已交付部分内容，不重连）: %w", streamErr)
+		}
+		if attempt >= c.MaxRetries {
+			return fmt.Errorf("sse 流中断: %w", streamErr)
+		}
+		if !sleepOrDone(ctx, delay) {
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// consume 把一次连接的响应体逐帧喂给 onEvent，记录下最后一个 id 供重连使用。
+func consume(body io.Reader, onEvent func(Event) error, lastEventID *string) error {
+	decoder := NewDecoder(body)
+	for {
+		event, err := decoder.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if event.ID != "" {
+			*lastEventID = event.ID
+		}
+		if err := onEvent(event); err != nil {
+			return errStop
+		}
+	}
+}
+
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
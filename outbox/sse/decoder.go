@@ -0,0 +1,106 @@
+// Package sse 实现一个遵循 WHATWG Server-Sent Events 规范的解析器和带自动重连的
+// 客户端，供 outbox 包内所有消费 SSE 流的地方（OpenRouter 的流式响应等）共用，
+// 取代此前各处手写的 `line[:6] == "data: "` 判断。
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Event 是 Decoder 按规范解析出的一帧完整事件。
+type Event struct {
+	ID    string
+	Name  string // 未显式指定 event: 字段时默认为 "message"
+	Data  string // 连续的 data: 行已用 \n 拼接
+	Retry int    // 毫秒；本帧没有 retry: 字段时为 0
+}
+
+// Decoder 从 io.Reader 里逐帧解析 SSE 流：以空行分隔事件，连续的 data: 行用 \n
+// 拼接，以 : 开头的行是注释会被忽略，id: 字段的值会被记住并沿用到没有带 id 的
+// 后续帧，和规范里浏览器 EventSource 的行为一致。
+type Decoder struct {
+	scanner     *bufio.Scanner
+	lastEventID string
+}
+
+// NewDecoder 创建一个 Decoder。内部用一个放大过的 buffer，避免 OpenRouter 偶尔
+// 吐出的超长单行撞上 bufio.MaxScanTokenSize 的默认限制。
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &Decoder{scanner: scanner}
+}
+
+// LastEventID 返回目前为止解析到的最后一个 id，断线重连时应带进 Last-Event-ID 头。
+func (d *Decoder) LastEventID() string {
+	return d.lastEventID
+}
+
+// Next 解析出下一帧事件；流正常结束时返回 io.EOF。
+func (d *Decoder) Next() (Event, error) {
+	event := Event{Name: "message"}
+	var data []string
+	haveContent := false
+
+	flush := func() Event {
+		event.Data = strings.Join(data, "\n")
+		if event.ID != "" {
+			d.lastEventID = event.ID
+		} else {
+			event.ID = d.lastEventID
+		}
+		return event
+	}
+
+	for d.scanner.Scan() {
+		line := d.scanner.Text()
+		if line == "" {
+			if !haveContent {
+				continue // 连续空行之间没有内容，按规范跳过
+			}
+			return flush(), nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // 注释行
+		}
+
+		field, value := splitField(line)
+		switch field {
+		case "data":
+			data = append(data, value)
+			haveContent = true
+		case "event":
+			event.Name = value
+			haveContent = true
+		case "id":
+			event.ID = value
+			haveContent = true
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil {
+				event.Retry = ms
+				haveContent = true
+			}
+		}
+	}
+	if err := d.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	if haveContent {
+		return flush(), nil
+	}
+	return Event{}, io.EOF
+}
+
+// splitField 把一行 "field: value" 拆成字段名和值，冒号后至多一个空格按规范会被去掉。
+func splitField(line string) (field, value string) {
+	idx := strings.IndexByte(line, ':')
+	if idx == -1 {
+		return line, ""
+	}
+	field = line[:idx]
+	value = strings.TrimPrefix(line[idx+1:], " ")
+	return field, value
+}
@@ -0,0 +1,84 @@
+package sse
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoderParsesMultiLineDataJoinedWithNewline(t *testing.T) {
+	d := NewDecoder(strings.NewReader("data: line1\ndata: line2\n\n"))
+	event, err := d.Next()
+	if err != nil {
+		t.Fatalf("解析第一帧失败: %v", err)
+	}
+	if event.Data != "line1\nline2" {
+		t.Fatalf("期望多行 data 用 \\n 拼接成 %q，实际 %q", "line1\nline2", event.Data)
+	}
+	if event.Name != "message" {
+		t.Fatalf("未显式指定 event: 字段时应该默认为 message，实际 %q", event.Name)
+	}
+}
+
+func TestDecoderParsesEventAndIDFields(t *testing.T) {
+	d := NewDecoder(strings.NewReader("id: 42\nevent: ping\ndata: hi\n\n"))
+	event, err := d.Next()
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if event.ID != "42" || event.Name != "ping" || event.Data != "hi" {
+		t.Fatalf("字段解析不对: %+v", event)
+	}
+}
+
+func TestDecoderIDCarriesOverToFollowingFramesWithoutID(t *testing.T) {
+	d := NewDecoder(strings.NewReader("id: 1\ndata: a\n\ndata: b\n\n"))
+
+	first, err := d.Next()
+	if err != nil || first.ID != "1" {
+		t.Fatalf("第一帧应该带 id=1: %+v, err=%v", first, err)
+	}
+
+	second, err := d.Next()
+	if err != nil {
+		t.Fatalf("解析第二帧失败: %v", err)
+	}
+	if second.ID != "1" {
+		t.Fatalf("没有显式 id 的后续帧应该沿用上一个 id，期望 1，实际 %q", second.ID)
+	}
+	if d.LastEventID() != "1" {
+		t.Fatalf("LastEventID() 应该是 1，实际 %q", d.LastEventID())
+	}
+}
+
+func TestDecoderIgnoresCommentLines(t *testing.T) {
+	d := NewDecoder(strings.NewReader(": this is a comment\ndata: hi\n\n"))
+	event, err := d.Next()
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if event.Data != "hi" {
+		t.Fatalf("注释行不应该影响 data，实际 %q", event.Data)
+	}
+}
+
+func TestDecoderReturnsEOFAtEndOfStream(t *testing.T) {
+	d := NewDecoder(strings.NewReader("data: only\n\n"))
+	if _, err := d.Next(); err != nil {
+		t.Fatalf("第一帧不应该出错: %v", err)
+	}
+	if _, err := d.Next(); err != io.EOF {
+		t.Fatalf("流结束后应该返回 io.EOF，实际 %v", err)
+	}
+}
+
+func TestDecoderParsesRetryField(t *testing.T) {
+	d := NewDecoder(strings.NewReader("retry: 3000\ndata: hi\n\n"))
+	event, err := d.Next()
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if event.Retry != 3000 {
+		t.Fatalf("期望 Retry=3000，实际 %d", event.Retry)
+	}
+}
@@ -0,0 +1,244 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"books-vector-api/outbox/sse"
+)
+
+// NewStreamTask 是 NewTask 的 SSE 流式版本：模型产出的文本以 token 为单位即时推给客户端，
+// 遇到 ask_user 工具调用时发出 tool_call 事件并暂停，直到 /planning/task/answer 把回答
+// 投递到该任务的 answer 通道后再恢复。每个任务由独立的 context 驱动，支持并发运行多个任务，
+// 客户端中断（ctx 取消）会立即打断正在进行的模型请求或 answer 等待。
+func NewStreamTask(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "读取请求体失败", http.StatusBadRequest)
+		return
+	}
+	var chatRequest struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(body, &chatRequest); err != nil {
+		http.Error(w, "解析请求体失败", http.StatusBadRequest)
+		return
+	}
+
+	stream, err := NewEventStream(w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stream.Heartbeat(15 * time.Second)
+	defer stream.Close()
+
+	taskID := GenerateUUID()
+	ctx, cancel := taskManager.Start(taskID)
+	defer cancel()
+	defer taskManager.Finish(taskID)
+
+	// 请求方的连接断开时一并取消任务。
+	ctx, cancelOnClientGone := context.WithCancel(ctx)
+	defer cancelOnClientGone()
+	go func() {
+		select {
+		case <-r.Context().Done():
+			cancelOnClientGone()
+		case <-ctx.Done():
+		}
+	}()
+
+	stream.Send("task_started", map[string]string{"taskID": taskID})
+
+	messages := []map[string]interface{}{
+		{"role": "system", "content": InteractivePlanningSystemPrompt},
+		{"role": "user", "content": chatRequest.Text},
+	}
+	for loop := 0; loop < maxOuterLoop; loop++ {
+		select {
+		case <-ctx.Done():
+			stream.Send("statusText", map[string]string{"payload": "任务中断"})
+			return
+		default:
+		}
+
+		completion, nextMessages, err := streamPlanningTask(ctx, stream, taskID, messages)
+		if err != nil {
+			stream.Send("error", map[string]string{"payload": err.Error()})
+			return
+		}
+		if completion.Done || completion.Break {
+			stream.Send("statusText", map[string]string{"payload": completion.Text})
+			return
+		}
+		messages = nextMessages
+	}
+	stream.Send("statusText", map[string]string{"payload": "任务中断: 最大循环数"})
+}
+
+// toolCallAccumulator 累加一个 tool_call 在各个流式 delta 里分散到达的 id/name/arguments 片段。
+type toolCallAccumulator struct {
+	id   string
+	name string
+	args strings.Builder
+}
+
+// streamPlanningTask 请求一次模型、流式转发文本增量，按 index 累加（可能并行的）tool_calls：
+// ask_user 交给 TaskManager 暂停等待人工回答，其余工具名在 defaultToolRegistry 里找到就直接
+// 执行，执行结果连同触发它的 assistant tool_calls 消息一起追加进 messages 再返回，调用方据此
+// 发起下一轮请求，直到模型不再要求调用工具或命中循环上限。
+func streamPlanningTask(ctx context.Context, stream *EventStream, taskID string, messages []map[string]interface{}) (RecursiveTaskCompletion, []map[string]interface{}, error) {
+	tools := append(append([]map[string]interface{}{}, Tool_Use_Case_Prompt["tools"].([]map[string]interface{})...), defaultToolRegistry.Schemas()...)
+	modelRequestBody, err := json.Marshal(map[string]interface{}{
+		"model":       ModelProviderModel,
+		"messages":    messages,
+		"stream":      true,
+		"temperature": 0,
+		"tools":       tools,
+		"tool_choice": "auto",
+	})
+	if err != nil {
+		return RecursiveTaskCompletion{}, messages, err
+	}
+
+	resp, err := doWithRetry(ctx, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ModelProviderURI, bytes.NewBuffer(modelRequestBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+ModelProviderAPIKey)
+		req.Header.Set("Content-Type", "application/json")
+		return http.DefaultClient.Do(req)
+	}, 3, 500*time.Millisecond)
+	if err != nil {
+		return RecursiveTaskCompletion{}, messages, fmt.Errorf("调用大模型失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var contentBuilder strings.Builder
+	accByIndex := make(map[int]*toolCallAccumulator)
+	var order []int
+	messageID := GenerateUUID()
+
+	decoder := sse.NewDecoder(resp.Body)
+	for {
+		select {
+		case <-ctx.Done():
+			return RecursiveTaskCompletion{}, messages, ctx.Err()
+		default:
+		}
+
+		event, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return RecursiveTaskCompletion{}, messages, fmt.Errorf("读取大模型响应失败: %w", err)
+		}
+		if event.Data == "[DONE]" {
+			break
+		}
+		var chunk ProviderStreamResponse
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil || len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" && delta.Content != "\n" {
+			stream.Send("text", map[string]string{"id": messageID, "content": delta.Content})
+			contentBuilder.WriteString(delta.Content)
+		}
+		for _, tc := range delta.ToolCalls {
+			acc, ok := accByIndex[tc.Index]
+			if !ok {
+				acc = &toolCallAccumulator{}
+				accByIndex[tc.Index] = acc
+				order = append(order, tc.Index)
+			}
+			if tc.ID != "" {
+				acc.id = tc.ID
+			}
+			if tc.Function.Name != "" {
+				acc.name = tc.Function.Name
+			}
+			acc.args.WriteString(tc.Function.Arguments)
+		}
+	}
+
+	if len(accByIndex) == 0 {
+		return RecursiveTaskCompletion{Text: contentBuilder.String(), Done: true}, messages, nil
+	}
+
+	// 按 OpenAI 的约定，先把带 tool_calls 的 assistant 消息写回历史，再逐个追加 role:tool 的结果，
+	// 下一轮请求才能让模型看到自己发起的调用和对应的返回值。
+	assistantToolCalls := make([]map[string]interface{}, 0, len(order))
+	for _, idx := range order {
+		acc := accByIndex[idx]
+		assistantToolCalls = append(assistantToolCalls, map[string]interface{}{
+			"id":       acc.id,
+			"type":     "function",
+			"function": map[string]interface{}{"name": acc.name, "arguments": acc.args.String()},
+		})
+	}
+	messages = append(messages, map[string]interface{}{
+		"role":       "assistant",
+		"content":    nil,
+		"tool_calls": assistantToolCalls,
+	})
+
+	for _, idx := range order {
+		acc := accByIndex[idx]
+
+		if acc.name == "ask_user" {
+			var toolArgs struct {
+				Question string `json:"question"`
+			}
+			if err := json.Unmarshal([]byte(acc.args.String()), &toolArgs); err != nil {
+				return RecursiveTaskCompletion{}, messages, fmt.Errorf("解析工具参数失败: %w", err)
+			}
+			stream.Send("tool_call", map[string]string{
+				"actionID": acc.id,
+				"name":     "ask_user",
+				"question": toolArgs.Question,
+			})
+			recordSessionEntry(taskID, MessageType{
+				Type:     "ask",
+				Payload:  PayloadType{Content: toolArgs.Question},
+				ActionID: acc.id,
+			})
+
+			answer, err := taskManager.WaitForAnswer(ctx, taskID, acc.id)
+			if err != nil {
+				return RecursiveTaskCompletion{Text: "回答超时或任务被中断", Break: true}, messages, nil
+			}
+			recordSessionEntry(taskID, answer)
+			reasonValue, _ := answer.Payload.Meta.Get("reason")
+			reason, _ := reasonValue.(string)
+			if reason == "" {
+				return RecursiveTaskCompletion{Text: "用户取消", Break: true}, messages, nil
+			}
+			messages = append(messages, map[string]interface{}{
+				"role": "tool", "tool_call_id": acc.id, "content": reason,
+			})
+			continue
+		}
+
+		stream.Send("tool_call", map[string]string{"actionID": acc.id, "name": acc.name, "arguments": acc.args.String()})
+		result, err := defaultToolRegistry.Invoke(ctx, acc.name, acc.args.String())
+		if err != nil {
+			result = "工具调用失败: " + err.Error()
+		}
+		stream.Send("tool_result", map[string]string{"actionID": acc.id, "name": acc.name, "content": result})
+		messages = append(messages, map[string]interface{}{
+			"role": "tool", "tool_call_id": acc.id, "content": result,
+		})
+	}
+
+	return RecursiveTaskCompletion{}, messages, nil
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// taskState 持有单个任务的取消函数，以及等待 ask_user 回答的 channel（按 actionID 索引）。
+type taskState struct {
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	answerChans map[string]chan MessageType
+}
+
+// TaskManager 把任务从轮询某个共享状态切换为基于 channel 的即时恢复模式：
+// 每个任务（以 taskID/sessionID 为 key）有自己的 context，InterruptTask 取消 context
+// 即可让阻塞中的 HTTP 请求、SSE 扫描和 answer 等待立即退出，多个任务之间互不影响，
+// 不再依赖一个进程内只有一份的单例状态。
+type TaskManager struct {
+	mu    sync.Mutex
+	tasks map[string]*taskState
+}
+
+// NewTaskManager 创建一个空的任务管理器。
+func NewTaskManager() *TaskManager {
+	return &TaskManager{tasks: make(map[string]*taskState)}
+}
+
+// taskManager 是进程内唯一的任务管理器，/task 和 /task/stream 两条路径都用它登记自己的
+// taskID：二者各自生成的 UUID 互不冲突，没有必要维护两份相同的状态机。
+var taskManager = NewTaskManager()
+
+// Start 注册一个新任务并返回它的 context，任务结束后调用方需要调用 Finish 清理。
+func (m *TaskManager) Start(taskID string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	m.tasks[taskID] = &taskState{cancel: cancel, answerChans: make(map[string]chan MessageType)}
+	m.mu.Unlock()
+	return ctx, cancel
+}
+
+// Finish 清理任务状态，通常和 Start 成对出现在 defer 里。
+func (m *TaskManager) Finish(taskID string) {
+	m.mu.Lock()
+	delete(m.tasks, taskID)
+	m.mu.Unlock()
+}
+
+// Cancel 取消指定任务的 context，返回任务是否存在。
+func (m *TaskManager) Cancel(taskID string) bool {
+	m.mu.Lock()
+	state, ok := m.tasks[taskID]
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+	state.cancel()
+	return true
+}
+
+// WaitForAnswer 阻塞直到 actionID 对应的回答到达，或 ctx 被取消/超时。
+func (m *TaskManager) WaitForAnswer(ctx context.Context, taskID, actionID string) (MessageType, error) {
+	m.mu.Lock()
+	state, ok := m.tasks[taskID]
+	m.mu.Unlock()
+	if !ok {
+		return MessageType{}, fmt.Errorf("task not found: %s", taskID)
+	}
+
+	ch := make(chan MessageType, 1)
+	state.mu.Lock()
+	state.answerChans[actionID] = ch
+	state.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return MessageType{}, ctx.Err()
+	case answer := <-ch:
+		return answer, nil
+	}
+}
+
+// Answer 把用户的回答投递给对应任务里等待该 actionID 的 goroutine，返回是否有人在等待。
+func (m *TaskManager) Answer(taskID, actionID string, msg MessageType) bool {
+	m.mu.Lock()
+	state, ok := m.tasks[taskID]
+	m.mu.Unlock()
+	if !ok {
+		return false
+	}
+	state.mu.Lock()
+	ch, ok := state.answerChans[actionID]
+	delete(state.answerChans, actionID)
+	state.mu.Unlock()
+	if !ok {
+		return false
+	}
+	ch <- msg
+	return true
+}
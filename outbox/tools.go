@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Tool 是模型可以在 tool_calls 里调用的一个工具。JSONSchema 按 OpenAI function-calling
+// 的 tools[].function 格式描述参数，Invoke 执行实际调用并返回要喂回模型的文本结果。
+type Tool interface {
+	Name() string
+	JSONSchema() map[string]interface{}
+	Invoke(ctx context.Context, argsJSON string) (string, error)
+}
+
+// ToolRegistry 管理一组 Tool：渲染成请求体里的 tools 字段，并按名字把 tool_calls 分发
+// 给对应的 Invoke。
+type ToolRegistry struct {
+	tools  []Tool
+	byName map[string]Tool
+}
+
+// NewToolRegistry 用给定的工具列表构造一个 ToolRegistry。
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	r := &ToolRegistry{byName: make(map[string]Tool, len(tools))}
+	for _, t := range tools {
+		r.tools = append(r.tools, t)
+		r.byName[t.Name()] = t
+	}
+	return r
+}
+
+// Get 按名字查找工具。
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	t, ok := r.byName[name]
+	return t, ok
+}
+
+// Schemas 渲染出请求体里 "tools" 字段需要的结构。
+func (r *ToolRegistry) Schemas() []map[string]interface{} {
+	schemas := make([]map[string]interface{}, len(r.tools))
+	for i, t := range r.tools {
+		schemas[i] = map[string]interface{}{
+			"type":     "function",
+			"function": t.JSONSchema(),
+		}
+	}
+	return schemas
+}
+
+// Invoke 按名字找到工具并执行，找不到时返回一段能直接喂回模型的错误说明。
+func (r *ToolRegistry) Invoke(ctx context.Context, name, argsJSON string) (string, error) {
+	tool, ok := r.Get(name)
+	if !ok {
+		return "", fmt.Errorf("未注册的工具: %s", name)
+	}
+	return tool.Invoke(ctx, argsJSON)
+}
+
+// VectorSearchFunc 和 ExportAllDataFunc 是 outbox 对 main 包里既有能力的钩子，
+// 和 InitModelProvider 一样的接线方式，避免 outbox 反过来 import main。main.go 在
+// init() 里赋值，未赋值时对应工具调用会返回"未配置"错误而不是 panic。
+var (
+	VectorSearchFunc  func(ctx context.Context, query string, k int) (string, error)
+	ExportAllDataFunc func() error
+)
+
+// vectorSearchTool 把 /search 背后的语义检索能力暴露成模型可调用的工具。
+type vectorSearchTool struct{}
+
+func (vectorSearchTool) Name() string { return "vector_search" }
+
+func (vectorSearchTool) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "vector_search",
+		"description": "在 ChromaDB 里按语义相似度检索已存储的文本",
+		"parameters": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string", "description": "检索用的查询文本"},
+				"k":     map[string]interface{}{"type": "integer", "description": "返回结果数量，默认 5"},
+			},
+			"required": []string{"query"},
+		},
+	}
+}
+
+func (vectorSearchTool) Invoke(ctx context.Context, argsJSON string) (string, error) {
+	if VectorSearchFunc == nil {
+		return "", fmt.Errorf("vector_search 工具未配置")
+	}
+	var args struct {
+		Query string `json:"query"`
+		K     int    `json:"k"`
+	}
+	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+		return "", fmt.Errorf("解析参数失败: %w", err)
+	}
+	k := args.K
+	if k <= 0 {
+		k = 5
+	}
+	return VectorSearchFunc(ctx, args.Query, k)
+}
+
+// csvExportTool 把 ExportAllDataFunc 背后的全量导出暴露成工具。
+type csvExportTool struct{}
+
+func (csvExportTool) Name() string { return "csv_export" }
+
+func (csvExportTool) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "csv_export",
+		"description": "把所有已存储的文本数据导出成本地 CSV 文件",
+		"parameters": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	}
+}
+
+func (csvExportTool) Invoke(ctx context.Context, argsJSON string) (string, error) {
+	if ExportAllDataFunc == nil {
+		return "", fmt.Errorf("csv_export 工具未配置")
+	}
+	if err := ExportAllDataFunc(); err != nil {
+		return "", err
+	}
+	return "导出成功", nil
+}
+
+// httpGetTool 发起一次只读 HTTP GET 请求，不依赖任何 main 包钩子。
+type httpGetTool struct{}
+
+func (httpGetTool) Name() string { return "http_get" }
+
+func (httpGetTool) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "http_get",
+		"description": "对给定 URL 发起一次 HTTP GET 请求，返回状态码和响应内容",
+		"parameters": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"url": map[string]interface{}{"type": "string", "description": "要请求的完整 URL"},
+			},
+			"required": []string{"url"},
+		},
+	}
+}
+
+// httpGetMaxBody 限制读回的响应体大小，避免一次超长响应把上下文撑爆。
+const httpGetMaxBody = 4096
+
+func (httpGetTool) Invoke(ctx context.Context, argsJSON string) (string, error) {
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+		return "", fmt.Errorf("解析参数失败: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, args.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpGetMaxBody))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("HTTP %d\n%s", resp.StatusCode, string(body)), nil
+}
+
+// defaultToolRegistry 是 streamPlanningTask 默认装载的工具集，ask_user 继续走
+// Tool_Use_Case_Prompt 里的人工介入路径，不归这个 registry 管。
+var defaultToolRegistry = NewToolRegistry(vectorSearchTool{}, csvExportTool{}, httpGetTool{})
@@ -1,11 +1,12 @@
 package vector
 
 import (
+	"fmt"
 	"math"
 )
 
-// L2 归一
-func normalize(vec []float32) []float32 {
+// Normalize 做 L2 归一。
+func Normalize(vec []float32) []float32 {
 	sum := float32(0.0)
 	for _, v := range vec {
 		sum += v * v
@@ -22,12 +23,12 @@ func normalize(vec []float32) []float32 {
 }
 
 func cosineSimilarity(a, b []float32) float32 {
-	if len(a) != 768 || len(b) != 768 {
-		panic("向量维度必须为768")
+	if len(a) != len(b) {
+		panic(fmt.Sprintf("向量维度不一致: %d != %d", len(a), len(b)))
 	}
 
 	var dot float32
-	for i := 0; i < 768; i++ {
+	for i := range a {
 		dot += a[i] * b[i]
 	}
 	return dot
@@ -35,8 +36,8 @@ func cosineSimilarity(a, b []float32) float32 {
 
 // 相似度（阈值）计算
 func BatchCompare(target []float32, vector []float32, threshold float32) bool {
-	targetNorm := normalize(target)
-	vecNorm := normalize(vector)
+	targetNorm := Normalize(target)
+	vecNorm := Normalize(vector)
 
 	sim := cosineSimilarity(targetNorm, vecNorm)
 	return sim >= threshold
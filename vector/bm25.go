@@ -0,0 +1,161 @@
+package vector
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// BM25Index 维护一个 token -> postings 的倒排索引，用于在 /search 中和向量检索做混合召回。
+// 索引只保存在内存里，和 addToChromaDB 写入的 metadata 是同一份生命周期：调用方在写入/删除
+// 向量记录的同时调用 AddDocument/RemoveDocument 保持两者一致。
+type BM25Index struct {
+	mu sync.RWMutex
+
+	// postings[token][docID] = 该 token 在该文档中出现的次数
+	postings map[string]map[string]int
+	docLen   map[string]int
+	totalLen int
+}
+
+// NewBM25Index 创建一个空的倒排索引。
+func NewBM25Index() *BM25Index {
+	return &BM25Index{
+		postings: make(map[string]map[string]int),
+		docLen:   make(map[string]int),
+	}
+}
+
+var tokenPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+func tokenize(text string) []string {
+	return tokenPattern.FindAllString(strings.ToLower(text), -1)
+}
+
+// AddDocument 把一篇文档加入索引；如果该 id 已存在会先移除旧版本，保证可以覆盖写。
+func (idx *BM25Index) AddDocument(id string, text string) {
+	idx.RemoveDocument(id)
+
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	tf := make(map[string]int, len(tokens))
+	for _, tok := range tokens {
+		tf[tok]++
+	}
+	for tok, count := range tf {
+		if idx.postings[tok] == nil {
+			idx.postings[tok] = make(map[string]int)
+		}
+		idx.postings[tok][id] = count
+	}
+	idx.docLen[id] = len(tokens)
+	idx.totalLen += len(tokens)
+}
+
+// RemoveDocument 从索引中移除一篇文档的所有 postings。
+func (idx *BM25Index) RemoveDocument(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	length, exists := idx.docLen[id]
+	if !exists {
+		return
+	}
+	for tok, docs := range idx.postings {
+		if _, ok := docs[id]; ok {
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(idx.postings, tok)
+			}
+		}
+	}
+	delete(idx.docLen, id)
+	idx.totalLen -= length
+}
+
+// BM25Result 是一条 BM25 检索命中结果。
+type BM25Result struct {
+	ID    string
+	Score float64
+}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Search 对 query 分词后按 BM25 打分，返回按分数降序排列的前 topN 条结果。
+func (idx *BM25Index) Search(query string, topN int) []BM25Result {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	numDocs := len(idx.docLen)
+	if numDocs == 0 {
+		return nil
+	}
+	avgDocLen := float64(idx.totalLen) / float64(numDocs)
+
+	scores := make(map[string]float64)
+	for _, term := range tokenize(query) {
+		docs, ok := idx.postings[term]
+		if !ok {
+			continue
+		}
+		idf := math.Log(1 + (float64(numDocs)-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+		for docID, freq := range docs {
+			docLen := float64(idx.docLen[docID])
+			tfScore := float64(freq) * (bm25K1 + 1) / (float64(freq) + bm25K1*(1-bm25B+bm25B*docLen/avgDocLen))
+			scores[docID] += idf * tfScore
+		}
+	}
+
+	results := make([]BM25Result, 0, len(scores))
+	for docID, score := range scores {
+		results = append(results, BM25Result{ID: docID, Score: score})
+	}
+	sortBM25Results(results)
+	if topN > 0 && len(results) > topN {
+		results = results[:topN]
+	}
+	return results
+}
+
+func sortBM25Results(results []BM25Result) {
+	// 简单插入排序即可：结果集通常只有几十条，不值得引入 sort.Slice 的额外依赖面。
+	for i := 1; i < len(results); i++ {
+		for j := i; j > 0 && results[j].Score > results[j-1].Score; j-- {
+			results[j], results[j-1] = results[j-1], results[j]
+		}
+	}
+}
+
+// ReciprocalRankFusion 按 RRF 公式融合多路排序结果：score(d) = Σ 1/(k + rank_i(d))，
+// rank 从 1 开始计数。未出现在某一路结果里的文档，该路贡献为 0。
+func ReciprocalRankFusion(k int, rankedLists ...[]string) []string {
+	scores := make(map[string]float64)
+	order := make([]string, 0)
+	for _, list := range rankedLists {
+		for rank, id := range list {
+			if _, seen := scores[id]; !seen {
+				order = append(order, id)
+			}
+			scores[id] += 1.0 / float64(k+rank+1)
+		}
+	}
+
+	fused := make([]string, len(order))
+	copy(fused, order)
+	for i := 1; i < len(fused); i++ {
+		for j := i; j > 0 && scores[fused[j]] > scores[fused[j-1]]; j-- {
+			fused[j], fused[j-1] = fused[j-1], fused[j]
+		}
+	}
+	return fused
+}
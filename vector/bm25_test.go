@@ -0,0 +1,79 @@
+package vector
+
+import "testing"
+
+func TestBM25IndexSearchRanksMoreRelevantDocHigher(t *testing.T) {
+	idx := NewBM25Index()
+	idx.AddDocument("doc1", "猫 喜欢 吃 鱼")
+	idx.AddDocument("doc2", "猫 猫 猫 喜欢 睡觉")
+	idx.AddDocument("doc3", "狗 喜欢 骨头")
+
+	results := idx.Search("猫", 10)
+	if len(results) != 2 {
+		t.Fatalf("期望命中 2 篇文档，实际 %d: %v", len(results), results)
+	}
+	if results[0].ID != "doc2" {
+		t.Fatalf("期望 doc2（出现 3 次“猫”）排第一，实际第一是 %s", results[0].ID)
+	}
+	if results[0].Score <= results[1].Score {
+		t.Fatalf("doc2 的分数应该高于 doc1: doc2=%v doc1=%v", results[0].Score, results[1].Score)
+	}
+}
+
+func TestBM25IndexAddDocumentOverwritesExisting(t *testing.T) {
+	idx := NewBM25Index()
+	idx.AddDocument("doc1", "猫")
+	idx.AddDocument("doc1", "狗")
+
+	if results := idx.Search("猫", 10); len(results) != 0 {
+		t.Fatalf("doc1 已经被覆盖成不含“猫”，不应该再命中，实际命中: %v", results)
+	}
+	if results := idx.Search("狗", 10); len(results) != 1 || results[0].ID != "doc1" {
+		t.Fatalf("覆盖后 doc1 应该命中“狗”，实际: %v", results)
+	}
+}
+
+func TestBM25IndexRemoveDocument(t *testing.T) {
+	idx := NewBM25Index()
+	idx.AddDocument("doc1", "猫 喜欢 鱼")
+	idx.RemoveDocument("doc1")
+
+	if results := idx.Search("猫", 10); len(results) != 0 {
+		t.Fatalf("移除后不应该再命中任何结果，实际: %v", results)
+	}
+}
+
+func TestBM25IndexSearchRespectsTopN(t *testing.T) {
+	idx := NewBM25Index()
+	idx.AddDocument("doc1", "猫")
+	idx.AddDocument("doc2", "猫")
+	idx.AddDocument("doc3", "猫")
+
+	results := idx.Search("猫", 2)
+	if len(results) != 2 {
+		t.Fatalf("topN=2 时应该只返回 2 条，实际 %d", len(results))
+	}
+}
+
+func TestReciprocalRankFusionPrefersDocRankedHighInBothLists(t *testing.T) {
+	vectorRanked := []string{"a", "b", "c"}
+	bm25Ranked := []string{"b", "a", "c"}
+
+	fused := ReciprocalRankFusion(60, vectorRanked, bm25Ranked)
+	if len(fused) != 3 {
+		t.Fatalf("期望融合出 3 个文档，实际 %d: %v", len(fused), fused)
+	}
+	if fused[0] != "a" && fused[0] != "b" {
+		t.Fatalf("两路都排在前两名的 a/b 应该排在单路都没进前二的 c 前面，实际第一名: %s", fused[0])
+	}
+	if fused[2] != "c" {
+		t.Fatalf("两路都排最后的 c 应该融合后也排最后，实际: %v", fused)
+	}
+}
+
+func TestReciprocalRankFusionKeepsDocsMissingFromSomeLists(t *testing.T) {
+	fused := ReciprocalRankFusion(60, []string{"a", "b"}, []string{"c"})
+	if len(fused) != 3 {
+		t.Fatalf("只在一路里出现的文档也要保留，期望 3 个，实际 %d: %v", len(fused), fused)
+	}
+}
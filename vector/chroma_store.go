@@ -0,0 +1,187 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ChromaStore 是 Store 的默认实现，直接对接 ChromaDB 的 HTTP API。
+// 这是把原先散落在 main.go 里的 addToChromaDB/vectorSearch/vectorCompare/listDocuments/deleteRecord
+// 请求逻辑迁移过来的结果；Upsert 调用 Chroma 的 upsert 接口，id 已存在时覆盖原记录。
+type ChromaStore struct {
+	BaseURL      string
+	Tenant       string
+	Database     string
+	CollectionID string
+}
+
+// NewChromaStore 创建一个指向指定 collection 的 ChromaStore。
+func NewChromaStore(baseURL, tenant, database, collectionID string) *ChromaStore {
+	return &ChromaStore{
+		BaseURL:      baseURL,
+		Tenant:       tenant,
+		Database:     database,
+		CollectionID: collectionID,
+	}
+}
+
+func (c *ChromaStore) collectionURL(action string) string {
+	return fmt.Sprintf("%s/tenants/%s/databases/%s/collections/%s/%s", c.BaseURL, c.Tenant, c.Database, c.CollectionID, action)
+}
+
+func (c *ChromaStore) Upsert(id string, embedding []float32, metadata map[string]string) error {
+	payload := struct {
+		Ids        []string            `json:"ids"`
+		Embeddings [][]float32         `json:"embeddings"`
+		Metadatas  []map[string]string `json:"metadatas,omitempty"`
+	}{
+		Ids:        []string{id},
+		Embeddings: [][]float32{embedding},
+		Metadatas:  []map[string]string{metadata},
+	}
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(c.collectionURL("upsert"), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("failed to add to chromadb: %s", string(bodyBytes))
+}
+
+type chromaQueryResponse struct {
+	IDs        [][]string                 `json:"ids"`
+	Embeddings [][][]float32              `json:"embeddings"`
+	Metadatas  [][]map[string]interface{} `json:"metadatas"`
+	Distances  [][]float32                `json:"distances"`
+}
+
+func (c *ChromaStore) Query(embedding []float32, k int, filter map[string]interface{}) ([]QueryResult, error) {
+	payload := struct {
+		QueryEmbeddings [][]float32            `json:"query_embeddings"`
+		NResults        int                    `json:"n_results"`
+		Where           map[string]interface{} `json:"where,omitempty"`
+		Include         []string               `json:"include"`
+	}{
+		QueryEmbeddings: [][]float32{embedding},
+		NResults:        k,
+		Where:           filter,
+		Include:         []string{"embeddings", "metadatas", "distances"},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Post(c.collectionURL("query"), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response chromaQueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	var results []QueryResult
+	for i := range response.IDs {
+		for j := range response.IDs[i] {
+			result := QueryResult{ID: response.IDs[i][j]}
+			if i < len(response.Metadatas) && j < len(response.Metadatas[i]) {
+				result.Metadata = response.Metadatas[i][j]
+			}
+			if i < len(response.Distances) && j < len(response.Distances[i]) {
+				result.Distance = response.Distances[i][j]
+			}
+			if i < len(response.Embeddings) && j < len(response.Embeddings[i]) {
+				result.Embedding = response.Embeddings[i][j]
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (c *ChromaStore) Get(ids []string, include []string) (GetResult, error) {
+	payload := struct {
+		IDs     []string `json:"ids"`
+		Include []string `json:"include"`
+	}{
+		IDs:     ids,
+		Include: include,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return GetResult{}, err
+	}
+	resp, err := http.Post(c.collectionURL("get"), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return GetResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		IDs        []string                 `json:"ids"`
+		Embeddings [][]float32              `json:"embeddings"`
+		Metadatas  []map[string]interface{} `json:"metadatas"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return GetResult{}, err
+	}
+	return GetResult{IDs: response.IDs, Embeddings: response.Embeddings, Metadatas: response.Metadatas}, nil
+}
+
+func (c *ChromaStore) Delete(ids []string) error {
+	payload := struct {
+		IDs []string `json:"ids"`
+	}{IDs: ids}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(c.collectionURL("delete"), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP request failed with status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *ChromaStore) List(limit int, offset int) (GetResult, error) {
+	payload := struct {
+		Limit   int      `json:"limit"`
+		Offset  int      `json:"offset"`
+		Include []string `json:"include"`
+	}{
+		Limit:   limit,
+		Offset:  offset,
+		Include: []string{"metadatas"},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return GetResult{}, err
+	}
+	resp, err := http.Post(c.collectionURL("get"), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return GetResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		IDs       []string                 `json:"ids"`
+		Metadatas []map[string]interface{} `json:"metadatas"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return GetResult{}, err
+	}
+	return GetResult{IDs: response.IDs, Metadatas: response.Metadatas}, nil
+}
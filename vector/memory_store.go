@@ -0,0 +1,187 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// MemoryStore 是一个进程内的 Store 实现，不依赖任何外部服务，主要用于本地开发和联调，
+// 这样运行服务时不必额外起一个 ChromaDB 实例。数据不落盘，重启即丢失。
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]memoryRecord
+}
+
+type memoryRecord struct {
+	embedding []float32
+	metadata  map[string]string
+}
+
+// NewMemoryStore 创建一个空的内存向量库。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]memoryRecord)}
+}
+
+func (m *MemoryStore) Upsert(id string, embedding []float32, metadata map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[id] = memoryRecord{embedding: embedding, metadata: metadata}
+	return nil
+}
+
+func (m *MemoryStore) Query(embedding []float32, k int, filter map[string]interface{}) ([]QueryResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make([]QueryResult, 0, len(m.records))
+	for id, rec := range m.records {
+		if !matchesFilter(rec.metadata, filter) {
+			continue
+		}
+		results = append(results, QueryResult{
+			ID:        id,
+			Metadata:  toInterfaceMap(rec.metadata),
+			Distance:  cosineDistance(embedding, rec.embedding),
+			Embedding: rec.embedding,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Distance < results[j].Distance })
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+func (m *MemoryStore) Get(ids []string, include []string) (GetResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := GetResult{}
+	for _, id := range ids {
+		rec, ok := m.records[id]
+		if !ok {
+			return GetResult{}, fmt.Errorf("record not found: %s", id)
+		}
+		result.IDs = append(result.IDs, id)
+		result.Embeddings = append(result.Embeddings, rec.embedding)
+		result.Metadatas = append(result.Metadatas, toInterfaceMap(rec.metadata))
+	}
+	return result, nil
+}
+
+func (m *MemoryStore) Delete(ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.records, id)
+	}
+	return nil
+}
+
+func (m *MemoryStore) List(limit int, offset int) (GetResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.records))
+	for id := range m.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	if offset >= len(ids) {
+		return GetResult{}, nil
+	}
+	end := offset + limit
+	if limit <= 0 || end > len(ids) {
+		end = len(ids)
+	}
+
+	result := GetResult{}
+	for _, id := range ids[offset:end] {
+		rec := m.records[id]
+		result.IDs = append(result.IDs, id)
+		result.Metadatas = append(result.Metadatas, toInterfaceMap(rec.metadata))
+	}
+	return result, nil
+}
+
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// matchesFilter 实现一个极简的 Chroma 风格 where 子句：{"field": {"$eq": "value"}}，
+// 以及 withTenantFilter 拼出的 {"$and": [where, {...}]} 组合形式（递归校验每个子 filter）。
+// 未识别的操作符一律视为不匹配，避免误报。
+func matchesFilter(metadata map[string]string, filter map[string]interface{}) bool {
+	for field, cond := range filter {
+		if field == "$and" {
+			if !matchesAnd(metadata, cond) {
+				return false
+			}
+			continue
+		}
+		condMap, ok := cond.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		want, ok := condMap["$eq"]
+		if !ok {
+			return false
+		}
+		if fmt.Sprintf("%v", want) != metadata[field] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAnd 校验 $and 操作符的子句列表，要求全部子 filter 都匹配。
+func matchesAnd(metadata map[string]string, cond interface{}) bool {
+	subFilters, ok := cond.([]map[string]interface{})
+	if !ok {
+		rawSubFilters, ok := cond.([]interface{})
+		if !ok {
+			return true
+		}
+		for _, raw := range rawSubFilters {
+			subFilter, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if !matchesFilter(metadata, subFilter) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, subFilter := range subFilters {
+		if !matchesFilter(metadata, subFilter) {
+			return false
+		}
+	}
+	return true
+}
+
+// cosineDistance 返回 1 - cosine similarity，数值越小越相似，和 Chroma 的 distances 语义保持一致。
+func cosineDistance(a, b []float32) float32 {
+	if len(a) == 0 || len(b) == 0 || len(a) != len(b) {
+		return 1
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	sim := dot / (math.Sqrt(normA) * math.Sqrt(normB))
+	return float32(1 - sim)
+}
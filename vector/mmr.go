@@ -0,0 +1,69 @@
+package vector
+
+// MMRCandidate 是参与最大边际相关性重排序的一个候选项，Embedding 需要已经做过 L2 归一。
+type MMRCandidate struct {
+	ID        string
+	Embedding []float32
+}
+
+// MMR 在 candidates 中迭代选出 k 个，使得每一步都最大化
+// λ·sim(d, query) − (1−λ)·max_{d'∈selected} sim(d, d')，从而在保持相关性的同时增加结果多样性。
+// query 和 candidates 的 Embedding 都应已经过 Normalize，sim 用点积（等价于 cosine）计算。
+func MMR(query []float32, candidates []MMRCandidate, k int, lambda float32) []string {
+	if k <= 0 || len(candidates) == 0 {
+		return nil
+	}
+	if k > len(candidates) {
+		k = len(candidates)
+	}
+
+	selected := make([]string, 0, k)
+	selectedIdx := make(map[int]bool, k)
+
+	for len(selected) < k {
+		bestIdx := -1
+		bestScore := float32(0)
+
+		for i, cand := range candidates {
+			if selectedIdx[i] {
+				continue
+			}
+			relevance := dot(cand.Embedding, query)
+
+			var maxSim float32
+			for j := range candidates {
+				if !selectedIdx[j] {
+					continue
+				}
+				if sim := dot(cand.Embedding, candidates[j].Embedding); sim > maxSim {
+					maxSim = sim
+				}
+			}
+
+			score := lambda*relevance - (1-lambda)*maxSim
+			if bestIdx == -1 || score > bestScore {
+				bestIdx = i
+				bestScore = score
+			}
+		}
+
+		if bestIdx == -1 {
+			break
+		}
+		selected = append(selected, candidates[bestIdx].ID)
+		selectedIdx[bestIdx] = true
+	}
+
+	return selected
+}
+
+func dot(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
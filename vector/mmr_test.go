@@ -0,0 +1,65 @@
+package vector
+
+import "testing"
+
+func TestMMRPrefersRelevanceWhenLambdaIsOne(t *testing.T) {
+	query := []float32{1, 0}
+	candidates := []MMRCandidate{
+		{ID: "a", Embedding: []float32{1, 0}},
+		{ID: "b", Embedding: []float32{0.9, 0}},
+		{ID: "c", Embedding: []float32{0, 1}},
+	}
+
+	selected := MMR(query, candidates, 2, 1)
+	if len(selected) != 2 {
+		t.Fatalf("期望选出 2 个，实际 %d: %v", len(selected), selected)
+	}
+	if selected[0] != "a" || selected[1] != "b" {
+		t.Fatalf("λ=1 时应该纯按相关性排序，期望 [a b]，实际 %v", selected)
+	}
+}
+
+func TestMMRAvoidsRedundancyWhenLambdaIsZero(t *testing.T) {
+	query := []float32{1, 0}
+	candidates := []MMRCandidate{
+		{ID: "a", Embedding: []float32{1, 0}},
+		{ID: "b", Embedding: []float32{1, 0}}, // 和 a 完全重复
+		{ID: "c", Embedding: []float32{0, 1}}, // 和已选集合最不相似
+	}
+
+	selected := MMR(query, candidates, 2, 0)
+	if len(selected) != 2 {
+		t.Fatalf("期望选出 2 个，实际 %d: %v", len(selected), selected)
+	}
+	if selected[1] != "c" {
+		t.Fatalf("λ=0 时第二个应该选多样性最高的 c（避开和 a 重复的 b），实际 %v", selected)
+	}
+}
+
+func TestMMRClampsKToCandidateCount(t *testing.T) {
+	query := []float32{1, 0}
+	candidates := []MMRCandidate{
+		{ID: "a", Embedding: []float32{1, 0}},
+	}
+
+	selected := MMR(query, candidates, 5, 0.5)
+	if len(selected) != 1 {
+		t.Fatalf("k 超过候选数时应该截断到候选数，期望 1 个，实际 %d: %v", len(selected), selected)
+	}
+}
+
+func TestMMRReturnsNilForEmptyCandidatesOrNonPositiveK(t *testing.T) {
+	if got := MMR([]float32{1, 0}, nil, 3, 0.5); got != nil {
+		t.Fatalf("候选为空应该返回 nil，实际 %v", got)
+	}
+	candidates := []MMRCandidate{{ID: "a", Embedding: []float32{1, 0}}}
+	if got := MMR([]float32{1, 0}, candidates, 0, 0.5); got != nil {
+		t.Fatalf("k<=0 应该返回 nil，实际 %v", got)
+	}
+}
+
+func TestDotReturnsZeroForMismatchedLengths(t *testing.T) {
+	if got := dot([]float32{1, 2}, []float32{1}); got != 0 {
+		t.Fatalf("维度不一致时 dot 应该返回 0，实际 %v", got)
+	}
+}
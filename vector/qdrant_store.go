@@ -0,0 +1,224 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// QdrantStore 对接 Qdrant 的 REST API（/collections/{name}/points 系列接口）。
+type QdrantStore struct {
+	BaseURL    string
+	Collection string
+}
+
+// NewQdrantStore 创建一个指向指定 collection 的 QdrantStore，要求该 collection 已预先创建。
+func NewQdrantStore(baseURL, collection string) *QdrantStore {
+	return &QdrantStore{BaseURL: baseURL, Collection: collection}
+}
+
+func (q *QdrantStore) pointsURL(action string) string {
+	if action == "" {
+		return fmt.Sprintf("%s/collections/%s/points", q.BaseURL, q.Collection)
+	}
+	return fmt.Sprintf("%s/collections/%s/points/%s", q.BaseURL, q.Collection, action)
+}
+
+func (q *QdrantStore) Upsert(id string, embedding []float32, metadata map[string]string) error {
+	payload := struct {
+		Points []qdrantPoint `json:"points"`
+	}{
+		Points: []qdrantPoint{{ID: id, Vector: embedding, Payload: toInterfaceMap(metadata)}},
+	}
+	body, _ := json.Marshal(payload)
+	resp, err := httpPut(q.pointsURL(""), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to upsert point in qdrant: %s", string(bodyBytes))
+	}
+	return nil
+}
+
+type qdrantPoint struct {
+	ID      string                 `json:"id"`
+	Vector  []float32              `json:"vector"`
+	Payload map[string]interface{} `json:"payload,omitempty"`
+}
+
+func (q *QdrantStore) Query(embedding []float32, k int, filter map[string]interface{}) ([]QueryResult, error) {
+	payload := struct {
+		Vector      []float32              `json:"vector"`
+		Limit       int                    `json:"limit"`
+		WithPayload bool                   `json:"with_payload"`
+		WithVector  bool                   `json:"with_vector"`
+		Filter      map[string]interface{} `json:"filter,omitempty"`
+	}{
+		Vector:      embedding,
+		Limit:       k,
+		WithPayload: true,
+		WithVector:  true,
+		Filter:      toQdrantFilter(filter),
+	}
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(q.pointsURL("search"), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result []struct {
+			ID      string                 `json:"id"`
+			Score   float32                `json:"score"`
+			Vector  []float32              `json:"vector"`
+			Payload map[string]interface{} `json:"payload"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	results := make([]QueryResult, len(response.Result))
+	for i, r := range response.Result {
+		// Qdrant 的 score 是相似度（越大越好），转换为和 Chroma 一致的距离语义（越小越好）。
+		results[i] = QueryResult{ID: r.ID, Metadata: r.Payload, Distance: 1 - r.Score, Embedding: r.Vector}
+	}
+	return results, nil
+}
+
+func (q *QdrantStore) Get(ids []string, include []string) (GetResult, error) {
+	payload := struct {
+		IDs         []string `json:"ids"`
+		WithVector  bool     `json:"with_vector"`
+		WithPayload bool     `json:"with_payload"`
+	}{
+		IDs:         ids,
+		WithVector:  contains(include, "embeddings"),
+		WithPayload: contains(include, "metadatas"),
+	}
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(q.pointsURL(""), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return GetResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result []struct {
+			ID      string                 `json:"id"`
+			Vector  []float32              `json:"vector"`
+			Payload map[string]interface{} `json:"payload"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return GetResult{}, err
+	}
+
+	result := GetResult{}
+	for _, r := range response.Result {
+		result.IDs = append(result.IDs, r.ID)
+		result.Embeddings = append(result.Embeddings, r.Vector)
+		result.Metadatas = append(result.Metadatas, r.Payload)
+	}
+	return result, nil
+}
+
+func (q *QdrantStore) Delete(ids []string) error {
+	payload := struct {
+		Points []string `json:"points"`
+	}{Points: ids}
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(q.pointsURL("delete"), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP request failed with status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (q *QdrantStore) List(limit int, offset int) (GetResult, error) {
+	payload := struct {
+		Limit       int  `json:"limit"`
+		Offset      int  `json:"offset,omitempty"`
+		WithPayload bool `json:"with_payload"`
+	}{
+		Limit:       limit,
+		Offset:      offset,
+		WithPayload: true,
+	}
+	body, _ := json.Marshal(payload)
+	resp, err := http.Post(q.pointsURL("scroll"), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return GetResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result struct {
+			Points []struct {
+				ID      string                 `json:"id"`
+				Payload map[string]interface{} `json:"payload"`
+			} `json:"points"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return GetResult{}, err
+	}
+
+	result := GetResult{}
+	for _, p := range response.Result.Points {
+		result.IDs = append(result.IDs, p.ID)
+		result.Metadatas = append(result.Metadatas, p.Payload)
+	}
+	return result, nil
+}
+
+func toQdrantFilter(filter map[string]interface{}) map[string]interface{} {
+	if len(filter) == 0 {
+		return nil
+	}
+	must := make([]map[string]interface{}, 0, len(filter))
+	for field, cond := range filter {
+		condMap, ok := cond.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if eq, ok := condMap["$eq"]; ok {
+			must = append(must, map[string]interface{}{
+				"key":   field,
+				"match": map[string]interface{}{"value": eq},
+			})
+		}
+	}
+	if len(must) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"must": must}
+}
+
+func contains(list []string, target string) bool {
+	for _, item := range list {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+func httpPut(url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return http.DefaultClient.Do(req)
+}
@@ -0,0 +1,34 @@
+package vector
+
+// Store 抽象了向量持久化层需要的全部能力，使上层业务逻辑（addToChromaDB、vectorSearch 等）
+// 不再与某一个具体的向量数据库绑定，方便在本地开发时用内存实现替代，或者在生产环境切换到
+// Qdrant / Weaviate / Milvus 等其它后端。
+type Store interface {
+	// Upsert 写入（或覆盖）一条记录。metadata 沿用现有 ChromaDB 的 string->string 约定。
+	Upsert(id string, embedding []float32, metadata map[string]string) error
+	// Query 按向量相似度检索最接近的 k 条记录，filter 为 Chroma 风格的 where 子句，
+	// 不支持过滤的后端可以忽略该参数。
+	Query(embedding []float32, k int, filter map[string]interface{}) ([]QueryResult, error)
+	// Get 按 id 精确获取记录，include 控制返回哪些字段（如 "embeddings"、"metadatas"）。
+	Get(ids []string, include []string) (GetResult, error)
+	// Delete 按 id 删除记录。
+	Delete(ids []string) error
+	// List 分页列出记录，用于 /documents、/export 等遍历场景。
+	List(limit int, offset int) (GetResult, error)
+}
+
+// QueryResult 是 Query 返回的单条检索结果。Embedding 在 MMR 重排序等需要做向量间两两
+// 比较的场景下使用，调用方如果不需要可以忽略。
+type QueryResult struct {
+	ID        string
+	Metadata  map[string]interface{}
+	Distance  float32
+	Embedding []float32
+}
+
+// GetResult 是 Get/List 返回的批量结果，字段是否填充取决于 include 参数。
+type GetResult struct {
+	IDs        []string
+	Embeddings [][]float32
+	Metadatas  []map[string]interface{}
+}